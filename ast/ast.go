@@ -3,6 +3,8 @@ package ast
 import (
 	"bytes"
 	"github.com/masa-suzu/monkey/token"
+	"math/big"
+	"sort"
 	"strings"
 )
 
@@ -51,6 +53,12 @@ type IntegerLiteral struct {
 	Value int64
 }
 
+// BigIntegerLiteral is an integer literal too large for an int64.
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -112,6 +120,7 @@ type CallExpression struct {
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression // Keys of Pairs in source order
 }
 
 func (p *Program) TokenLiteral() string {
@@ -176,6 +185,10 @@ func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+func (bl *BigIntegerLiteral) expressionNode()      {}
+func (bl *BigIntegerLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntegerLiteral) String() string       { return bl.Token.Literal }
+
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return "\"" + sl.Token.Literal + "\"" }
@@ -313,8 +326,8 @@ func (hl *HashLiteral) String() string {
 
 	pairs := []string{}
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.OrderedKeys() {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -323,6 +336,22 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 }
 
+// OrderedKeys returns the keys of Pairs in source order.
+// Literals built without Keys have their keys sorted by String() instead.
+func (hl *HashLiteral) OrderedKeys() []Expression {
+	if len(hl.Keys) == len(hl.Pairs) {
+		return hl.Keys
+	}
+	keys := []Expression{}
+	for key := range hl.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
 type MacroLiteral struct {
 	Token      token.Token // The 'macro' token
 	Parameters []*Identifier
@@ -0,0 +1,129 @@
+package ast
+
+// Copy returns a deep copy of node, so that the copy can be modified without
+// changing node. Leaves such as literals are copied too.
+func Copy(node Node) Node {
+	switch node := node.(type) {
+	case *Program:
+		return &Program{Statements: copyStatements(node.Statements)}
+	case *LetStatement:
+		return &LetStatement{Token: node.Token, Name: copyIdentifier(node.Name), Value: copyExpression(node.Value)}
+	case *ReturnStatement:
+		return &ReturnStatement{Token: node.Token, ReturnValue: copyExpression(node.ReturnValue)}
+	case *ExpressionStatement:
+		return &ExpressionStatement{Token: node.Token, Expression: copyExpression(node.Expression)}
+	case *BlockStatement:
+		return copyBlock(node)
+	case *Identifier:
+		return copyIdentifier(node)
+	case *IntegerLiteral:
+		copied := *node
+		return &copied
+	case *BigIntegerLiteral:
+		copied := *node
+		return &copied
+	case *StringLiteral:
+		copied := *node
+		return &copied
+	case *Boolean:
+		copied := *node
+		return &copied
+	case *PrefixExpression:
+		return &PrefixExpression{Token: node.Token, Operator: node.Operator, Right: copyExpression(node.Right)}
+	case *InfixExpression:
+		return &InfixExpression{
+			Token:    node.Token,
+			Left:     copyExpression(node.Left),
+			Operator: node.Operator,
+			Right:    copyExpression(node.Right),
+		}
+	case *IfExpression:
+		return &IfExpression{
+			Token:       node.Token,
+			Condition:   copyExpression(node.Condition),
+			Consequence: copyBlock(node.Consequence),
+			Alternative: copyBlock(node.Alternative),
+		}
+	case *FunctionLiteral:
+		return &FunctionLiteral{Token: node.Token, Parameters: copyIdentifiers(node.Parameters), Body: copyBlock(node.Body)}
+	case *MacroLiteral:
+		return &MacroLiteral{Token: node.Token, Parameters: copyIdentifiers(node.Parameters), Body: copyBlock(node.Body)}
+	case *ArrayLiteral:
+		return &ArrayLiteral{Token: node.Token, Elements: copyExpressions(node.Elements)}
+	case *IndexExpression:
+		return &IndexExpression{Token: node.Token, Left: copyExpression(node.Left), Index: copyExpression(node.Index)}
+	case *CallExpression:
+		return &CallExpression{
+			Token:     node.Token,
+			Function:  copyExpression(node.Function),
+			Arguments: copyExpressions(node.Arguments),
+		}
+	case *HashLiteral:
+		copied := &HashLiteral{Token: node.Token, Pairs: map[Expression]Expression{}}
+		for _, key := range node.OrderedKeys() {
+			newKey := copyExpression(key)
+			copied.Pairs[newKey] = copyExpression(node.Pairs[key])
+			copied.Keys = append(copied.Keys, newKey)
+		}
+		return copied
+	default:
+		return node
+	}
+}
+
+func copyExpression(e Expression) Expression {
+	if e == nil {
+		return nil
+	}
+	return Copy(e).(Expression)
+}
+
+func copyExpressions(es []Expression) []Expression {
+	if es == nil {
+		return nil
+	}
+	copied := make([]Expression, len(es))
+	for i, e := range es {
+		copied[i] = copyExpression(e)
+	}
+	return copied
+}
+
+func copyStatements(ss []Statement) []Statement {
+	if ss == nil {
+		return nil
+	}
+	copied := make([]Statement, len(ss))
+	for i, s := range ss {
+		if s != nil {
+			copied[i] = Copy(s).(Statement)
+		}
+	}
+	return copied
+}
+
+func copyBlock(b *BlockStatement) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	return &BlockStatement{Token: b.Token, Statements: copyStatements(b.Statements)}
+}
+
+func copyIdentifier(i *Identifier) *Identifier {
+	if i == nil {
+		return nil
+	}
+	copied := *i
+	return &copied
+}
+
+func copyIdentifiers(is []*Identifier) []*Identifier {
+	if is == nil {
+		return nil
+	}
+	copied := make([]*Identifier, len(is))
+	for i, identifier := range is {
+		copied[i] = copyIdentifier(identifier)
+	}
+	return copied
+}
@@ -0,0 +1,58 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+
+	nodes := []Node{
+		&Program{Statements: []Statement{
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: one()},
+			&ReturnStatement{ReturnValue: one()},
+		}},
+		&InfixExpression{Left: one(), Operator: "+", Right: &PrefixExpression{Operator: "-", Right: one()}},
+		&IfExpression{
+			Condition:   one(),
+			Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+		},
+		&FunctionLiteral{
+			Parameters: []*Identifier{{Value: "x"}},
+			Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+		},
+		&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one(), &StringLiteral{Value: "a"}}},
+		&IndexExpression{Left: &ArrayLiteral{Elements: []Expression{one()}}, Index: one()},
+	}
+
+	for _, node := range nodes {
+		copied := Copy(node)
+		if !reflect.DeepEqual(copied, node) {
+			t.Errorf("copy is not equal. got=%#v, want=%#v", copied, node)
+			continue
+		}
+
+		Modify(copied, turnOneIntoTwo)
+		if reflect.DeepEqual(copied, node) {
+			t.Errorf("modifying the copy changed the original. got=%#v", node)
+		}
+	}
+}
+
+func TestCopyHash(t *testing.T) {
+	key := &IntegerLiteral{Value: 1}
+	hash := &HashLiteral{Pairs: map[Expression]Expression{key: &IntegerLiteral{Value: 1}}, Keys: []Expression{key}}
+
+	copied := Copy(hash).(*HashLiteral)
+	Modify(copied, turnOneIntoTwo)
+
+	if key.Value != 1 || hash.Pairs[key].(*IntegerLiteral).Value != 1 {
+		t.Errorf("modifying the copy changed the original. got=%#v", hash)
+	}
+	for k, v := range copied.Pairs {
+		if k == key || k.(*IntegerLiteral).Value != 2 || v.(*IntegerLiteral).Value != 2 {
+			t.Errorf("wrong copy. got=%#v", copied)
+		}
+	}
+}
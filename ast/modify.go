@@ -1,56 +1,164 @@
 package ast
 
+import (
+	"fmt"
+)
+
 type NodeModifier func(Node) Node
 
-func Modify(from Node, modify NodeModifier) Node {
-	// TODO: remove _ in type assertions.
+// A ModifyError reports a modifier replacing a node with one that its parent
+// can not hold, such as an identifier of a let statement with an expression.
+type ModifyError struct {
+	Parent Node
+	Field  string // the field of Parent holding the node, like "Arguments[1]"
+	Got    Node
+	Want   string
+}
+
+func (e *ModifyError) Error() string {
+	got := "nil"
+	if e.Got != nil {
+		got = fmt.Sprintf("%T", e.Got)
+	}
+	return fmt.Sprintf("ast: can not put %s in %T.%s, want %s", got, e.Parent, e.Field, e.Want)
+}
+
+// Modify replaces every node in from, children first, with the result of
+// modify. Nodes that do not fit where they would go are left as they were and
+// reported with the first ModifyError.
+func Modify(from Node, modify NodeModifier) (Node, error) {
+	m := &modifier{modify: modify}
+	node := m.apply(from)
+	return node, m.err
+}
+
+type modifier struct {
+	modify NodeModifier
+	err    error
+}
+
+func (m *modifier) apply(from Node) Node {
 	switch from := from.(type) {
 	case *Program:
-		for i, _ := range from.Statements {
-			from.Statements[i], _ = Modify(from.Statements[i], modify).(Statement)
+		for i, statement := range from.Statements {
+			from.Statements[i] = m.statement(from, "Statements", i, statement)
 		}
 	case *BlockStatement:
 		for i, statement := range from.Statements {
-			from.Statements[i], _ = Modify(statement, modify).(Statement)
+			from.Statements[i] = m.statement(from, "Statements", i, statement)
 		}
 	case *ReturnStatement:
-		from.ReturnValue, _ = Modify(from.ReturnValue, modify).(Expression)
+		from.ReturnValue = m.expression(from, "ReturnValue", -1, from.ReturnValue)
 	case *LetStatement:
-		from.Value, _ = Modify(from.Value, modify).(Expression)
+		from.Name = m.identifier(from, "Name", -1, from.Name)
+		from.Value = m.expression(from, "Value", -1, from.Value)
 	case *ExpressionStatement:
-		from.Expression, _ = Modify(from.Expression, modify).(Expression)
+		from.Expression = m.expression(from, "Expression", -1, from.Expression)
 	case *InfixExpression:
-		from.Left, _ = Modify(from.Left, modify).(Expression)
-		from.Right, _ = Modify(from.Right, modify).(Expression)
+		from.Left = m.expression(from, "Left", -1, from.Left)
+		from.Right = m.expression(from, "Right", -1, from.Right)
 	case *PrefixExpression:
-		from.Right, _ = Modify(from.Right, modify).(Expression)
+		from.Right = m.expression(from, "Right", -1, from.Right)
 	case *IndexExpression:
-		from.Left, _ = Modify(from.Left, modify).(Expression)
-		from.Index, _ = Modify(from.Index, modify).(Expression)
+		from.Left = m.expression(from, "Left", -1, from.Left)
+		from.Index = m.expression(from, "Index", -1, from.Index)
 	case *IfExpression:
-		from.Condition, _ = Modify(from.Condition, modify).(Expression)
-		from.Consequence, _ = Modify(from.Consequence, modify).(*BlockStatement)
-		if from.Alternative != nil {
-			from.Alternative, _ = Modify(from.Alternative, modify).(*BlockStatement)
-		}
+		from.Condition = m.expression(from, "Condition", -1, from.Condition)
+		from.Consequence = m.block(from, "Consequence", from.Consequence)
+		from.Alternative = m.block(from, "Alternative", from.Alternative)
 	case *FunctionLiteral:
-		for i, _ := range from.Parameters {
-			from.Parameters[i], _ = Modify(from.Parameters[i], modify).(*Identifier)
+		for i, parameter := range from.Parameters {
+			from.Parameters[i] = m.identifier(from, "Parameters", i, parameter)
+		}
+		from.Body = m.block(from, "Body", from.Body)
+	case *MacroLiteral:
+		for i, parameter := range from.Parameters {
+			from.Parameters[i] = m.identifier(from, "Parameters", i, parameter)
+		}
+		from.Body = m.block(from, "Body", from.Body)
+	case *CallExpression:
+		from.Function = m.expression(from, "Function", -1, from.Function)
+		for i, argument := range from.Arguments {
+			from.Arguments[i] = m.expression(from, "Arguments", i, argument)
 		}
-		from.Body, _ = Modify(from.Body, modify).(*BlockStatement)
 	case *ArrayLiteral:
-		for i, _ := range from.Elements {
-			from.Elements[i], _ = Modify(from.Elements[i], modify).(Expression)
+		for i, element := range from.Elements {
+			from.Elements[i] = m.expression(from, "Elements", i, element)
 		}
 	case *HashLiteral:
 		newPairs := make(map[Expression]Expression)
-		for key, val := range from.Pairs {
-			newKey, _ := Modify(key, modify).(Expression)
-			newVal, _ := Modify(val, modify).(Expression)
+		newKeys := []Expression{}
+		for _, key := range from.OrderedKeys() {
+			val := from.Pairs[key]
+			newKey := m.expression(from, "Keys", len(newKeys), key)
+			newVal := m.expression(from, "Pairs", len(newKeys), val)
 			newPairs[newKey] = newVal
+			newKeys = append(newKeys, newKey)
 		}
 		from.Pairs = newPairs
+		from.Keys = newKeys
 	}
 
-	return modify(from)
+	return m.modify(from)
+}
+
+// The methods below modify a child of parent, found in field at index unless
+// index is negative. Missing children are skipped.
+
+func (m *modifier) statement(parent Node, field string, index int, s Statement) Statement {
+	if s == nil {
+		return nil
+	}
+	result := m.apply(s)
+	if modified, ok := result.(Statement); ok {
+		return modified
+	}
+	m.fail(parent, field, index, result, "Statement")
+	return s
+}
+
+func (m *modifier) expression(parent Node, field string, index int, e Expression) Expression {
+	if e == nil {
+		return nil
+	}
+	result := m.apply(e)
+	if modified, ok := result.(Expression); ok {
+		return modified
+	}
+	m.fail(parent, field, index, result, "Expression")
+	return e
+}
+
+func (m *modifier) identifier(parent Node, field string, index int, i *Identifier) *Identifier {
+	if i == nil {
+		return nil
+	}
+	result := m.apply(i)
+	if modified, ok := result.(*Identifier); ok && modified != nil {
+		return modified
+	}
+	m.fail(parent, field, index, result, "*ast.Identifier")
+	return i
+}
+
+func (m *modifier) block(parent Node, field string, b *BlockStatement) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	result := m.apply(b)
+	if modified, ok := result.(*BlockStatement); ok && modified != nil {
+		return modified
+	}
+	m.fail(parent, field, -1, result, "*ast.BlockStatement")
+	return b
+}
+
+func (m *modifier) fail(parent Node, field string, index int, got Node, want string) {
+	if m.err != nil {
+		return
+	}
+	if index >= 0 {
+		field = fmt.Sprintf("%s[%d]", field, index)
+	}
+	m.err = &ModifyError{Parent: parent, Field: field, Got: got, Want: want}
 }
@@ -99,6 +99,32 @@ func TestModify(t *testing.T) {
 				},
 			},
 		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), one()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: one()},
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: two()},
+		},
+		{
+			&MacroLiteral{
+				Parameters: []*Identifier{{Value: "x"}},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&MacroLiteral{
+				Parameters: []*Identifier{{Value: "x"}},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
 		{
 			&ArrayLiteral{Elements: []Expression{one(), one()}},
 			&ArrayLiteral{Elements: []Expression{two(), two()}},
@@ -106,7 +132,11 @@ func TestModify(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		modified := Modify(tt.input, turnOneIntoTwo)
+		modified, err := Modify(tt.input, turnOneIntoTwo)
+		if err != nil {
+			t.Errorf("got error: %s", err)
+			continue
+		}
 
 		equal := reflect.DeepEqual(modified, tt.expected)
 		if !equal {
@@ -142,6 +172,76 @@ func TestModifyHash(t *testing.T) {
 	}
 }
 
+func TestModifyErrors(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	name := func() *Identifier { return &Identifier{Value: "x"} }
+	identifierToOne := func(node Node) Node {
+		if _, ok := node.(*Identifier); ok {
+			return one()
+		}
+		return node
+	}
+	statementToNil := func(node Node) Node {
+		if _, ok := node.(*ExpressionStatement); ok {
+			return nil
+		}
+		return node
+	}
+
+	tests := []struct {
+		input    Node
+		modify   NodeModifier
+		expected string
+	}{
+		{
+			&LetStatement{Name: name(), Value: one()},
+			identifierToOne,
+			"ast: can not put *ast.IntegerLiteral in *ast.LetStatement.Name, want *ast.Identifier",
+		},
+		{
+			&FunctionLiteral{Parameters: []*Identifier{name(), name()}, Body: &BlockStatement{}},
+			identifierToOne,
+			"ast: can not put *ast.IntegerLiteral in *ast.FunctionLiteral.Parameters[0], want *ast.Identifier",
+		},
+		{
+			&Program{Statements: []Statement{&LetStatement{Value: one()}, &ExpressionStatement{Expression: one()}}},
+			statementToNil,
+			"ast: can not put nil in *ast.Program.Statements[1], want Statement",
+		},
+		{
+			&CallExpression{Function: name(), Arguments: []Expression{one()}},
+			func(node Node) Node {
+				if _, ok := node.(*IntegerLiteral); ok {
+					return &ExpressionStatement{}
+				}
+				return node
+			},
+			"ast: can not put *ast.ExpressionStatement in *ast.CallExpression.Arguments[0], want Expression",
+		},
+	}
+
+	for _, tt := range tests {
+		modified, err := Modify(tt.input, tt.modify)
+		if err == nil {
+			t.Errorf("expected an error for %#v", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+		if modified != tt.input {
+			t.Errorf("the modified node is replaced. got=%#v", modified)
+		}
+	}
+
+	// Nodes which do not fit are left as they were.
+	let := &LetStatement{Name: name(), Value: one()}
+	Modify(let, identifierToOne)
+	if let.Name == nil || let.Name.Value != "x" {
+		t.Errorf("name of let is replaced. got=%#v", let.Name)
+	}
+}
+
 func turnOneIntoTwo(node Node) Node {
 	integer, ok := node.(*IntegerLiteral)
 	if !ok {
@@ -0,0 +1,111 @@
+package ast
+
+// A Visitor's Visit method is called by Walk for each node. If the result w
+// is not nil, Walk visits the children of node with w, followed by a call of
+// w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order, without
+// changing it. Use Modify to rewrite a tree.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkExpression(v, n.Value)
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *FunctionLiteral:
+		walkFunction(v, n.Parameters, n.Body)
+	case *MacroLiteral:
+		walkFunction(v, n.Parameters, n.Body)
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+	case *HashLiteral:
+		for _, key := range n.OrderedKeys() {
+			walkExpression(v, key)
+			walkExpression(v, n.Pairs[key])
+		}
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling
+// f(node) for each node and f(nil) after its children. The children of a node
+// are skipped if f returns false for it.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+func walkStatements(v Visitor, statements []Statement) {
+	for _, s := range statements {
+		if s != nil {
+			Walk(v, s)
+		}
+	}
+}
+
+func walkExpression(v Visitor, e Expression) {
+	if e != nil {
+		Walk(v, e)
+	}
+}
+
+func walkExpressions(v Visitor, expressions []Expression) {
+	for _, e := range expressions {
+		walkExpression(v, e)
+	}
+}
+
+func walkFunction(v Visitor, parameters []*Identifier, body *BlockStatement) {
+	for _, parameter := range parameters {
+		if parameter != nil {
+			Walk(v, parameter)
+		}
+	}
+	if body != nil {
+		Walk(v, body)
+	}
+}
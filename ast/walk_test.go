@@ -0,0 +1,90 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	one := &IntegerLiteral{Value: 1}
+	x := &Identifier{Value: "x"}
+	f := &Identifier{Value: "f"}
+	body := &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: x}}}
+	fn := &FunctionLiteral{Parameters: []*Identifier{x}, Body: body}
+	call := &CallExpression{Function: f, Arguments: []Expression{one}}
+	let := &LetStatement{Name: f, Value: fn}
+	program := &Program{Statements: []Statement{let, &ExpressionStatement{Expression: call}}}
+
+	var visited []Node
+	Inspect(program, func(node Node) bool {
+		if node != nil {
+			visited = append(visited, node)
+		}
+		return true
+	})
+
+	expected := []Node{
+		program, let, f, fn, x, body, body.Statements[0], x,
+		program.Statements[1], call, f, one,
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong nodes visited.\nwant=%#v\ngot=%#v", expected, visited)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	x := &Identifier{Value: "x"}
+	macro := &MacroLiteral{
+		Parameters: []*Identifier{x},
+		Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: x}}},
+	}
+	array := &ArrayLiteral{Elements: []Expression{macro, x}}
+
+	count := 0
+	Inspect(array, func(node Node) bool {
+		if _, ok := node.(*Identifier); ok {
+			count++
+		}
+		_, isMacro := node.(*MacroLiteral)
+		return !isMacro
+	})
+	if count != 1 {
+		t.Errorf("expected 1 identifier outside the macro, got=%d", count)
+	}
+}
+
+type depthVisitor struct {
+	depth *int
+	max   *int
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+	*v.depth++
+	if *v.depth > *v.max {
+		*v.max = *v.depth
+	}
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	// ((1 + 2) * -3) is three levels deep: the product, the sum and the
+	// negation, and their integers.
+	expression := &InfixExpression{
+		Left:     &InfixExpression{Left: &IntegerLiteral{Value: 1}, Operator: "+", Right: &IntegerLiteral{Value: 2}},
+		Operator: "*",
+		Right:    &PrefixExpression{Operator: "-", Right: &IntegerLiteral{Value: 3}},
+	}
+
+	depth, max := 0, 0
+	Walk(depthVisitor{&depth, &max}, expression)
+	if max != 3 {
+		t.Errorf("expected depth=3, got=%d", max)
+	}
+	if depth != 0 {
+		t.Errorf("Visit(nil) is not called for every node, depth=%d", depth)
+	}
+}
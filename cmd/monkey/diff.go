@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around changes.
+const contextLines = 3
+
+// An edit is a line kept (' '), removed ('-') or added ('+').
+type edit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff returns the changes turning a, named from, into b, named to,
+// in the unified format of diff -u. It returns "" if a and b are equal.
+func unifiedDiff(from, to, a, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	// aLines[k] and bLines[k] count the lines of a and b before edits[k].
+	aLines := make([]int, len(edits)+1)
+	bLines := make([]int, len(edits)+1)
+	for k, e := range edits {
+		aLines[k+1], bLines[k+1] = aLines[k], bLines[k]
+		if e.op != '+' {
+			aLines[k+1]++
+		}
+		if e.op != '-' {
+			bLines[k+1]++
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		start := k - contextLines
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk while the next change is close enough for their
+		// contexts to meet.
+		end, unchanged := k, 0
+		for end < len(edits) && unchanged <= 2*contextLines {
+			if edits[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		end -= unchanged - contextLines
+		if unchanged < contextLines {
+			end = len(edits)
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(aLines[start], aLines[end]-aLines[start]),
+			hunkRange(bLines[start], bLines[end]-bLines[start]))
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		k = end
+	}
+	return out.String()
+}
+
+// maxDiffCost bounds the number of edits diffLines looks for between two
+// regions before giving up and replacing one region with the other, so that
+// files rewritten from top to bottom do not take quadratic time.
+const maxDiffCost = 1000
+
+// diffLines returns the shortest edits turning a into b, found by the linear
+// space variant of Myers' algorithm.
+func diffLines(a, b []string) []edit {
+	d := &differ{a: a, b: b, edits: []edit{}}
+	d.compare(0, len(a), 0, len(b))
+	return d.edits
+}
+
+// A differ collects the edits turning a into b.
+type differ struct {
+	a, b  []string
+	edits []edit
+}
+
+// compare appends the edits turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.edits = append(d.edits, edit{' ', d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	end := aHi
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	if x, y, ok := d.middleSnake(aLo, aHi, bLo, bHi); ok {
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	} else {
+		for _, line := range d.a[aLo:aHi] {
+			d.edits = append(d.edits, edit{'-', line})
+		}
+		for _, line := range d.b[bLo:bHi] {
+			d.edits = append(d.edits, edit{'+', line})
+		}
+	}
+
+	for _, line := range d.a[aHi:end] {
+		d.edits = append(d.edits, edit{' ', line})
+	}
+}
+
+// middleSnake returns a point on a shortest path of edits through the
+// regions, which neither start nor end alike, by searching from both of
+// their ends until the searches meet. ok is false if a region is empty or
+// the path is longer than maxDiffCost.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	if limit > maxDiffCost {
+		limit = maxDiffCost
+	}
+
+	// forward[offset+k] is the furthest line of a reached on the diagonal
+	// where x-y is k, counting from the start, and backward[offset+k] the
+	// same counting from the ends. A diagonal k of one is delta-k of the
+	// other.
+	offset := limit + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for cost := 0; cost <= limit; cost++ {
+		for k := -cost; k <= cost; k += 2 {
+			x := furthest(forward[offset+k-1:], k, cost)
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if r := delta - k; odd && -cost < r && r < cost && x+backward[offset+r] >= n {
+				return aLo + x, bLo + y, true
+			}
+		}
+		for k := -cost; k <= cost; k += 2 {
+			x := furthest(backward[offset+k-1:], k, cost)
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if f := delta - k; !odd && -cost <= f && f <= cost && x+forward[offset+f] >= n {
+				return aHi - x, bHi - y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// furthest returns the line of a that a path of cost edits reaches on
+// diagonal k before following equal lines, by taking one more edit from the
+// further of its neighbours. around holds the diagonals k-1, k and k+1.
+func furthest(around []int, k, cost int) int {
+	if k == -cost || (k != cost && around[0] < around[2]) {
+		return around[2]
+	}
+	return around[0] + 1
+}
+
+// hunkRange formats the range of count lines after the first start lines
+// for a hunk header.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s after each line break, keeping the breaks.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
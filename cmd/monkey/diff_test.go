@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected string
+	}{
+		{"same\n", "same\n", ""},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n",
+			"1\nX\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n14\n15\n16\n17\n",
+			"@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n 4\n 5\n" +
+				"@@ -10,7 +10,7 @@\n 10\n 11\n 12\n-13\n 14\n 15\n 16\n+17\n",
+		},
+		{
+			"a\nb\nc\nd\ne\nf\ng\nh\n",
+			"a\nB\nc\nd\ne\nf\ng\nH\n",
+			"@@ -1,8 +1,8 @@\n a\n-b\n+B\n c\n d\n e\n f\n g\n-h\n+H\n",
+		},
+		{"", "x\n", "@@ -0,0 +1 @@\n+x\n"},
+		{"x\n", "", "@@ -1 +0,0 @@\n-x\n"},
+		{"x", "x\n", "@@ -1 +1 @@\n-x\n\\ No newline at end of file\n+x\n"},
+	}
+
+	for _, tt := range tests {
+		got := unifiedDiff("a", "b", tt.a, tt.b)
+		expected := tt.expected
+		if expected != "" {
+			expected = "--- a\n+++ b\n" + expected
+		}
+		if got != expected {
+			t.Errorf("diff of %q and %q\nexpected=%q\ngot=%q", tt.a, tt.b, expected, got)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(r), randomLines(r)
+		edits := diffLines(a, b)
+
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range edits {
+			if e.op != '+' {
+				gotA = append(gotA, e.line)
+			}
+			if e.op != '-' {
+				gotB = append(gotB, e.line)
+			}
+			if e.op != ' ' {
+				changes++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("edits of %q and %q do not turn one into the other: %v", a, b, edits)
+		}
+		if want := len(a) + len(b) - 2*longestCommon(a, b); changes != want {
+			t.Errorf("edits of %q and %q are not the shortest. want=%d, got=%d", a, b, want, changes)
+		}
+	}
+}
+
+func TestDiffLinesGivesUp(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20000; i++ {
+		a = append(a, fmt.Sprintf("a%d\n", i))
+		b = append(b, fmt.Sprintf("b%d\n", i))
+	}
+	edits := diffLines(append([]string{"same\n"}, a...), append([]string{"same\n"}, b...))
+	if len(edits) != 1+len(a)+len(b) || edits[0].op != ' ' || edits[1].op != '-' || edits[len(edits)-1].op != '+' {
+		t.Errorf("lines are not replaced in one block")
+	}
+}
+
+func randomLines(r *rand.Rand) []string {
+	lines := make([]string, r.Intn(12))
+	for i := range lines {
+		lines[i] = string(rune('a'+r.Intn(3))) + "\n"
+	}
+	return lines
+}
+
+// longestCommon returns the length of the longest common subsequence of a
+// and b.
+func longestCommon(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] > lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	return lcs[0][0]
+}
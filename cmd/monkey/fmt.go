@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/masa-suzu/monkey/formatter"
+	"github.com/masa-suzu/monkey/lexer"
+	"github.com/masa-suzu/monkey/parser"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fmtOptions are the flags of monkey fmt.
+type fmtOptions struct {
+	write bool
+	list  bool
+	diff  bool
+}
+
+// check reports whether files are only checked, so that monkey fmt fails if
+// one of them is not formatted.
+func (o fmtOptions) check() bool {
+	return (o.list || o.diff) && !o.write
+}
+
+// formatFiles formats the Monkey files named by args, and the .mk files in
+// the directories among them, or standard input if there are none.
+func formatFiles(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, "usage: monkey fmt [-w] [-l] [-d] [path...]\n")
+		flags.PrintDefaults()
+	}
+	var o fmtOptions
+	flags.BoolVar(&o.write, "w", false, "write the result to the files instead of standard output")
+	flags.BoolVar(&o.list, "l", false, "list the files whose formatting differs, failing unless -w is given")
+	flags.BoolVar(&o.diff, "d", false, "print diffs of the changes, failing unless -w is given")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if o.write {
+			fmt.Fprintln(stderr, "monkey fmt: can not use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			return 1
+		}
+		formatted, ok := formatSource("<standard input>", src, o, stdout, stderr)
+		if !ok || (formatted != string(src) && o.check()) {
+			return 1
+		}
+		return 0
+	}
+
+	failed := false
+	for _, path := range flags.Args() {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Directories are searched for .mk files, while files named
+			// explicitly are formatted whatever their extension.
+			if entry.IsDir() || (file != path && !strings.HasSuffix(file, ".mk")) {
+				return nil
+			}
+			changed, ok := formatFile(file, o, stdout, stderr)
+			if !ok || (changed && o.check()) {
+				failed = true
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// formatFile formats the file at path, writing it back if o.write is set.
+func formatFile(path string, o fmtOptions, stdout, stderr io.Writer) (changed bool, ok bool) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+		return false, false
+	}
+	formatted, ok := formatSource(path, src, o, stdout, stderr)
+	changed = formatted != string(src)
+	if !ok || !changed || !o.write {
+		return changed, ok
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		err = os.WriteFile(path, []byte(formatted), info.Mode().Perm())
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+		return changed, false
+	}
+	return changed, true
+}
+
+// formatSource formats src, read from name, and prints what o asks for: the
+// formatted source unless o has a flag set, the name if it changed with -l
+// and the changes with -d. ok is false if src could not be parsed.
+func formatSource(name string, src []byte, o fmtOptions, stdout, stderr io.Writer) (formatted string, ok bool) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "%s: %s\n", name, msg)
+		}
+		return "", false
+	}
+
+	formatted = formatter.Format(program, 0)
+	if formatted != "" {
+		formatted += "\n"
+	}
+	changed := formatted != string(src)
+
+	if !o.write && !o.list && !o.diff {
+		io.WriteString(stdout, formatted)
+	}
+	if changed && o.list {
+		fmt.Fprintln(stdout, name)
+	}
+	if changed && o.diff {
+		io.WriteString(stdout, unifiedDiff(name+".orig", name, string(src), formatted))
+	}
+	return formatted, true
+}
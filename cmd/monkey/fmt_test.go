@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	unformatted = "let x=1\nputs(x)"
+	formatted   = "let x = 1;\nputs(x);\n"
+)
+
+func TestFormatFiles(t *testing.T) {
+	tests := []struct {
+		args     []string
+		code     int
+		expected string
+		errors   string
+		written  bool
+	}{
+		{[]string{"{dir}/a.mk"}, 0, formatted, "", false},
+		{[]string{"-l", "{dir}"}, 1, "{dir}/a.mk\n", "", false},
+		{[]string{"-l", "{dir}/sub"}, 0, "", "", false},
+		{[]string{"-d", "{dir}/a.mk"}, 1, "--- {dir}/a.mk.orig\n+++ {dir}/a.mk\n@@ -1,2 +1,2 @@\n-let x=1\n-puts(x)\n\\ No newline at end of file\n+let x = 1;\n+puts(x);\n", "", false},
+		{[]string{"-w", "{dir}"}, 0, "", "", true},
+		{[]string{"-w", "-l", "{dir}"}, 0, "{dir}/a.mk\n", "", true},
+		{[]string{"{dir}/broken.txt"}, 1, "", "{dir}/broken.txt: no prefix parse function for ; found\n", false},
+		{[]string{"-l", "{dir}/missing.mk"}, 1, "", "monkey fmt: lstat {dir}/missing.mk: no such file or directory\n", false},
+	}
+
+	for _, tt := range tests {
+		dir, err := os.MkdirTemp("", "monkey")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		files := map[string]string{
+			"a.mk":       unformatted,
+			"sub/b.mk":   formatted,
+			"sub/c.txt":  unformatted,
+			"broken.txt": "let x = ;",
+		}
+		for name, src := range files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		args := []string{"fmt"}
+		for _, arg := range tt.args {
+			args = append(args, strings.Replace(arg, "{dir}", dir, 1))
+		}
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run(args, strings.NewReader(""), stdout, stderr)
+
+		if code != tt.code {
+			t.Errorf("%q exited with %d, want %d. stderr=%q", tt.args, code, tt.code, stderr.String())
+		}
+		if expected := strings.Replace(tt.expected, "{dir}", dir, -1); stdout.String() != expected {
+			t.Errorf("%q wrote %q, want %q", tt.args, stdout.String(), expected)
+		}
+		if errors := strings.Replace(tt.errors, "{dir}", dir, -1); stderr.String() != errors {
+			t.Errorf("%q wrote errors %q, want %q", tt.args, stderr.String(), errors)
+		}
+
+		src, err := os.ReadFile(filepath.Join(dir, "a.mk"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if written := string(src) == formatted; written != tt.written {
+			t.Errorf("%q left a.mk as %q", tt.args, src)
+		}
+		if src, _ := os.ReadFile(filepath.Join(dir, "sub/c.txt")); string(src) != unformatted {
+			t.Errorf("%q changed sub/c.txt to %q", tt.args, src)
+		}
+	}
+}
+
+func TestFormatStandardInput(t *testing.T) {
+	tests := []struct {
+		args     []string
+		stdin    string
+		code     int
+		expected string
+	}{
+		{[]string{}, unformatted, 0, formatted},
+		{[]string{}, "", 0, ""},
+		{[]string{"-l"}, unformatted, 1, "<standard input>\n"},
+		{[]string{"-l"}, formatted, 0, ""},
+		{[]string{"-d"}, formatted, 0, ""},
+		{[]string{"-w"}, unformatted, 2, ""},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run(append([]string{"fmt"}, tt.args...), strings.NewReader(tt.stdin), stdout, stderr)
+		if code != tt.code {
+			t.Errorf("%q exited with %d, want %d. stderr=%q", tt.args, code, tt.code, stderr.String())
+		}
+		if stdout.String() != tt.expected {
+			t.Errorf("%q wrote %q, want %q", tt.args, stdout.String(), tt.expected)
+		}
+	}
+}
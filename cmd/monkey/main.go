@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/masa-suzu/monkey"
+	"github.com/masa-suzu/monkey/object"
+	"github.com/masa-suzu/monkey/repl"
+	"io"
+	"os"
+)
+
+const usage = `usage:
+	monkey [-vm] [-debug]                 start the REPL
+	monkey [-vm] -e code [args...]        run code and print its value
+	monkey run [-vm] file.mk [args...]    run a file, or standard input if it is -
+	monkey fmt [-w] [-l] [-d] [path...]   format files, or standard input
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command given by args, returning its exit code: 1 if the
+// script failed and 2 if the command was wrong.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	useVM := flags.Bool("vm", false, "run on virtual machine")
+	debugMode := flags.Bool("debug", false, "dump instructions on virtual machine for each line run in the REPL")
+	expr := flags.String("e", "", "run `code` and print its value")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *debugMode && (*expr != "" || flags.NArg() != 0) {
+		fmt.Fprintln(stderr, "monkey: -debug is only supported by the REPL")
+		flags.Usage()
+		return 2
+	}
+
+	if *expr != "" {
+		return runScript(*expr, flags.Args(), *useVM, true, stdin, stdout, stderr)
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintf(stdout, "Hello! This is the Monkey programming language!\n")
+		fmt.Fprintf(stdout, "Type :help for the commands.\n")
+		repl.Start(stdin, stdout, ">> ", *useVM, *debugMode)
+		return 0
+	}
+
+	switch flags.Arg(0) {
+	case "run":
+		return runFile(flags.Args()[1:], *useVM, stdin, stdout, stderr)
+	case "fmt":
+		return formatFiles(flags.Args()[1:], stdin, stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "monkey: unknown command %q\n", flags.Arg(0))
+	flags.Usage()
+	return 2
+}
+
+// runFile runs the script named by the first of args, passing it the others.
+func runFile(args []string, useVM bool, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, "usage: monkey run [-vm] file.mk [args...]\n")
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&useVM, "vm", useVM, "run on virtual machine")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	var src []byte
+	var err error
+	if path == "-" {
+		src, err = io.ReadAll(stdin)
+		// The script is read from stdin, leaving nothing for gets.
+		stdin = nil
+	} else {
+		src, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return 1
+	}
+	return runScript(string(src), flags.Args()[1:], useVM, false, stdin, stdout, stderr)
+}
+
+// runScript runs src with args bound to a global array of strings named args,
+// printing the value of its last expression if print is true.
+func runScript(src string, args []string, useVM bool, print bool, stdin io.Reader, stdout, stderr io.Writer) int {
+	backend := monkey.BackendEvaluator
+	if useVM {
+		backend = monkey.BackendVM
+	}
+	i := monkey.New(monkey.WithBackend(backend), monkey.WithInput(stdin), monkey.WithOutput(stdout))
+
+	elements := make([]object.Object, len(args))
+	for index, arg := range args {
+		elements[index] = &object.String{Value: arg}
+	}
+	i.SetGlobal("args", &object.Array{Elements: elements})
+
+	value, err := i.Eval(src)
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return 1
+	}
+	if print && value.Type() != object.NULL_OBJ {
+		fmt.Fprintln(stdout, value.Inspect())
+	}
+	return 0
+}
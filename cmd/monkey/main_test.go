@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := os.MkdirTemp("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "greet.mk")
+	if err := os.WriteFile(script, []byte(`puts("Hello, " + args[0] + "!");`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.mk")
+	if err := os.WriteFile(broken, []byte("let x = ;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		stdin    string
+		code     int
+		expected string
+		errors   string
+	}{
+		{[]string{"-e", "1 + 2"}, "", 0, "3\n", ""},
+		{[]string{"-e", "let x = 1;"}, "", 0, "", ""},
+		{[]string{"-e", "len(args)", "a", "b"}, "", 0, "2\n", ""},
+		{[]string{"-e", "gets()"}, "monkey\n", 0, "monkey\n", ""},
+		{[]string{"run", script, "monkey"}, "", 0, "Hello, monkey!\n", ""},
+		{[]string{"run", "-vm", script, "monkey"}, "", 0, "Hello, monkey!\n", ""},
+		{[]string{"-vm", "run", script, "monkey"}, "", 0, "Hello, monkey!\n", ""},
+		{[]string{"run", "-", "stdin"}, `puts(args[0], gets())`, 0, "stdin\nnull\n", ""},
+		{[]string{"run", broken}, "", 1, "", "monkey: parse error: no prefix parse function for ; found\n"},
+		{[]string{"-e", "x"}, "", 1, "", "monkey: runtime error: identifier not found: x\n"},
+		{[]string{"-vm", "-e", "x"}, "", 1, "", "monkey: compile error: undefined variable x\n"},
+		{[]string{"-vm", "-e", "1 + true"}, "", 1, "", "monkey: runtime error: unsupported types for binary operation: INTEGER BOOLEAN\n"},
+		{[]string{"-e", "let m = macro() { 1 }; m(2)"}, "", 1, "", "monkey: macro error: "},
+		{[]string{"-e", "len(1); 5"}, "", 1, "", "monkey: runtime error: argument to `len` not supported, got INTEGER\n"},
+		{[]string{"-vm", "-e", "len(1); 5"}, "", 1, "", "monkey: runtime error: argument to `len` not supported, got INTEGER\n"},
+		{[]string{"-vm", "-e", `let x = len(1); puts("after")`}, "", 1, "", "monkey: runtime error: argument to `len` not supported, got INTEGER\n"},
+		{[]string{"-vm", "-e", "map([1, 2], len)"}, "", 1, "", "monkey: runtime error: argument to `len` not supported, got INTEGER\n"},
+		{[]string{"run", filepath.Join(dir, "missing.mk")}, "", 1, "", ""},
+		{[]string{"run"}, "", 2, "", "usage: monkey run"},
+		{[]string{"walk"}, "", 2, "", `monkey: unknown command "walk"`},
+		{[]string{"-nope"}, "", 2, "", "flag provided but not defined: -nope"},
+		{[]string{"-vm", "-debug", "-e", "1"}, "", 2, "", "monkey: -debug is only supported by the REPL\nusage:"},
+		{[]string{"-debug", "run", script}, "", 2, "", "monkey: -debug is only supported by the REPL\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run(tt.args, strings.NewReader(tt.stdin), stdout, stderr)
+		if code != tt.code {
+			t.Errorf("%q exited with %d, want %d. stderr=%q", tt.args, code, tt.code, stderr.String())
+		}
+		if stdout.String() != tt.expected {
+			t.Errorf("%q wrote %q, want %q", tt.args, stdout.String(), tt.expected)
+		}
+		if !strings.HasPrefix(stderr.String(), tt.errors) {
+			t.Errorf("%q wrote errors %q, want %q", tt.args, stderr.String(), tt.errors)
+		}
+		if tt.code == 1 && stderr.Len() == 0 {
+			t.Errorf("%q failed without an error", tt.args)
+		}
+	}
+}
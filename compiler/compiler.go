@@ -5,7 +5,6 @@ import (
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/code"
 	"github.com/masa-suzu/monkey/object"
-	"sort"
 )
 
 type EmittedInstruction struct {
@@ -244,6 +243,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(code.Closure, c.addConstant(compiledFn), len(freeSymbols))
 	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			return c.compileQuote(node)
+		}
 		err := c.Compile(node.Function)
 		if err != nil {
 			return err
@@ -266,6 +268,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.Constant, c.addConstant(integer))
+	case *ast.BigIntegerLiteral:
+		integer := &object.BigInt{Value: node.Value}
+		c.emit(code.Constant, c.addConstant(integer))
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.True)
@@ -291,16 +296,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 				err = c.Compile(n)
 			}
 		}
-		keys := []ast.Expression{}
-		for key := range node.Pairs {
-			keys = append(keys, key)
-		}
-
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-
-		for _, key := range keys {
+		for _, key := range node.OrderedKeys() {
 			compileNode(key)
 			compileNode(node.Pairs[key])
 			if err != nil {
@@ -327,6 +323,21 @@ func (c *Compiler) Compile(node ast.Node) error {
 	return nil
 }
 
+// ProducesValue reports whether the last statement of program leaves a value
+// behind on the VM. Otherwise the last value popped is a leftover of an
+// earlier statement.
+func ProducesValue(program *ast.Program) bool {
+	if len(program.Statements) == 0 {
+		return false
+	}
+	switch program.Statements[len(program.Statements)-1].(type) {
+	case *ast.ExpressionStatement, *ast.ReturnStatement:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *Compiler) ByteCode() *ByteCode {
 	return &ByteCode{
 		Instructions: c.currentInstructions(),
@@ -400,6 +411,33 @@ func (c *Compiler) changeOperand(opPos int, operand int) {
 	c.replaceInstruction(opPos, newIns)
 }
 
+// isQuoteCall reports whether call is a call of quote, which, as in the
+// evaluator, can not be shadowed.
+func isQuoteCall(call *ast.CallExpression) bool {
+	return call.Function.TokenLiteral() == "quote"
+}
+
+// compileQuote loads the quoted node as a constant. Unquoting needs the
+// evaluator, so the compiler does not support it.
+func (c *Compiler) compileQuote(call *ast.CallExpression) error {
+	if len(call.Arguments) != 1 {
+		return fmt.Errorf("wrong number of arguments to quote. got=%d, want=1", len(call.Arguments))
+	}
+	unquoted := false
+	ast.Inspect(call.Arguments[0], func(node ast.Node) bool {
+		if inner, ok := node.(*ast.CallExpression); ok {
+			name := inner.Function.TokenLiteral()
+			unquoted = unquoted || name == "unquote" || name == "unquote_splicing"
+		}
+		return !unquoted
+	})
+	if unquoted {
+		return fmt.Errorf("unquote is not supported by the compiler: %s", call)
+	}
+	c.emit(code.Constant, c.addConstant(&object.Quote{Node: call.Arguments[0]}))
+	return nil
+}
+
 func (c *Compiler) addConstant(obj object.Object) int {
 	c.constants = append(c.constants, obj)
 	return len(c.constants) - 1
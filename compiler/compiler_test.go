@@ -7,6 +7,7 @@ import (
 	"github.com/masa-suzu/monkey/lexer"
 	"github.com/masa-suzu/monkey/object"
 	"github.com/masa-suzu/monkey/parser"
+	"strings"
 	"testing"
 )
 
@@ -354,6 +355,18 @@ func TestHashLiterals(t *testing.T) {
 				code.Make(code.Pop),
 			},
 		},
+		{
+			input:             `{5:6,1:2}`,
+			expectedConstants: []interface{}{5, 6, 1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.Constant, 0),
+				code.Make(code.Constant, 1),
+				code.Make(code.Constant, 2),
+				code.Make(code.Constant, 3),
+				code.Make(code.Hash, 4),
+				code.Make(code.Pop),
+			},
+		},
 	}
 	runCompilerTest(t, tests)
 }
@@ -798,6 +811,70 @@ func TestBuiltins(t *testing.T) {
 	runCompilerTest(t, tests)
 }
 
+func TestByteCodeSerialization(t *testing.T) {
+	program := parse(`
+	let big = 123456789012345678901234567890;
+	let f = fn(x, y) { let z = x + y; [z, "monkey", -9223372036854775809] };
+	f(1, 2)
+	`)
+	c := New()
+	if err := c.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := c.ByteCode()
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary got error: %s", err)
+	}
+	decoded := &ByteCode{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary got error: %s", err)
+	}
+
+	if decoded.Instructions.String() != original.Instructions.String() {
+		t.Errorf("wrong instructions.\nwant=%s\ngot=%s", original.Instructions, decoded.Instructions)
+	}
+	if len(decoded.Constants) != len(original.Constants) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(original.Constants), len(decoded.Constants))
+	}
+	for i, want := range original.Constants {
+		got := decoded.Constants[i]
+		if got.Type() != want.Type() {
+			t.Errorf("constant %d has wrong type. want=%s, got=%s", i, want.Type(), got.Type())
+			continue
+		}
+		fn, ok := want.(*object.CompiledFunction)
+		if !ok {
+			if got.Inspect() != want.Inspect() {
+				t.Errorf("constant %d wrong. want=%s, got=%s", i, want.Inspect(), got.Inspect())
+			}
+			continue
+		}
+		decodedFn := got.(*object.CompiledFunction)
+		if decodedFn.Instructions.String() != fn.Instructions.String() {
+			t.Errorf("constant %d has wrong instructions.\nwant=%s\ngot=%s", i, fn.Instructions, decodedFn.Instructions)
+		}
+		if decodedFn.NumLocals != fn.NumLocals || decodedFn.NumParameters != fn.NumParameters {
+			t.Errorf("constant %d has wrong locals or parameters. want=%d/%d, got=%d/%d",
+				i, fn.NumLocals, fn.NumParameters, decodedFn.NumLocals, decodedFn.NumParameters)
+		}
+	}
+
+	for _, n := range []int{0, 3, len(data) - 1} {
+		if err := (&ByteCode{}).UnmarshalBinary(data[:n]); err == nil {
+			t.Errorf("UnmarshalBinary of %d bytes got no error", n)
+		}
+	}
+	old := append([]byte("MKBC\x01"), data[5:]...)
+	if err := (&ByteCode{}).UnmarshalBinary(old); err == nil || !strings.Contains(err.Error(), "version 1") {
+		t.Errorf("UnmarshalBinary of version 1 got error %v", err)
+	}
+	if _, err := (&ByteCode{Constants: []object.Object{&object.Array{}}}).MarshalBinary(); err == nil {
+		t.Errorf("MarshalBinary of an ARRAY constant got no error")
+	}
+}
+
 func runCompilerTest(t *testing.T, tests []compilerTestCase) {
 	t.Helper()
 
@@ -849,7 +926,7 @@ func testConstants(
 ) error {
 
 	if len(expected) != len(actual) {
-		return fmt.Errorf("wrong numbers of constants.\nwant=%q\ngot =%q", len(expected), len(actual))
+		return fmt.Errorf("wrong numbers of constants.\nwant=%d\ngot =%d", len(expected), len(actual))
 	}
 
 	for i, c := range expected {
@@ -0,0 +1,201 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/masa-suzu/monkey/code"
+	"github.com/masa-suzu/monkey/object"
+	"io"
+	"math/big"
+)
+
+// ByteCode is serialized as a magic number and a version, followed by the
+// instructions and the constant pool. Lengths and integers are varints.
+//
+// OpGetBuiltin refers to builtins by their index in object.Builtins, so the
+// version must be bumped whenever that slice is reordered or shrinks.
+// Version 2 dropped help and exit.
+
+var byteCodeMagic = []byte("MKBC")
+
+const byteCodeVersion byte = 2
+
+// Tags of the constants in a serialized constant pool.
+const (
+	tagInteger byte = iota + 1
+	tagBigInt
+	tagString
+	tagCompiledFunction
+)
+
+// MarshalBinary encodes the instructions and the constant pool of b.
+func (b *ByteCode) MarshalBinary() ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(byteCodeMagic)
+	out.WriteByte(byteCodeVersion)
+	writeBytes(&out, b.Instructions)
+	writeUvarint(&out, uint64(len(b.Constants)))
+	for _, constant := range b.Constants {
+		if err := writeConstant(&out, constant); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// UnmarshalBinary decodes data written by MarshalBinary into b.
+func (b *ByteCode) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, byteCodeMagic) || len(data) == len(byteCodeMagic) {
+		return fmt.Errorf("invalid bytecode: bad header")
+	}
+	if version := data[len(byteCodeMagic)]; version != byteCodeVersion {
+		return fmt.Errorf("unsupported bytecode version %d, want %d", version, byteCodeVersion)
+	}
+	r := bytes.NewReader(data[len(byteCodeMagic)+1:])
+
+	instructions, err := readBytes(r)
+	if err != nil {
+		return err
+	}
+	n, err := readUvarint(r)
+	if err != nil {
+		return err
+	}
+	constants := []object.Object{}
+	for i := uint64(0); i < n; i++ {
+		constant, err := readConstant(r)
+		if err != nil {
+			return err
+		}
+		constants = append(constants, constant)
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("invalid bytecode: %d bytes left over", r.Len())
+	}
+
+	b.Instructions = instructions
+	b.Constants = constants
+	return nil
+}
+
+func writeConstant(out *bytes.Buffer, constant object.Object) error {
+	switch constant := constant.(type) {
+	case *object.Integer:
+		out.WriteByte(tagInteger)
+		var buf [binary.MaxVarintLen64]byte
+		out.Write(buf[:binary.PutVarint(buf[:], constant.Value)])
+	case *object.BigInt:
+		out.WriteByte(tagBigInt)
+		if constant.Value.Sign() < 0 {
+			out.WriteByte(1)
+		} else {
+			out.WriteByte(0)
+		}
+		writeBytes(out, constant.Value.Bytes())
+	case *object.String:
+		out.WriteByte(tagString)
+		writeBytes(out, []byte(constant.Value))
+	case *object.CompiledFunction:
+		out.WriteByte(tagCompiledFunction)
+		writeUvarint(out, uint64(constant.NumLocals))
+		writeUvarint(out, uint64(constant.NumParameters))
+		writeBytes(out, constant.Instructions)
+	default:
+		return fmt.Errorf("unsupported constant type %s", constant.Type())
+	}
+	return nil
+}
+
+func readConstant(r *bytes.Reader) (object.Object, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, truncated(err)
+	}
+	switch tag {
+	case tagInteger:
+		value, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, truncated(err)
+		}
+		return &object.Integer{Value: value}, nil
+	case tagBigInt:
+		sign, err := r.ReadByte()
+		if err != nil {
+			return nil, truncated(err)
+		}
+		magnitude, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		value := new(big.Int).SetBytes(magnitude)
+		if sign == 1 {
+			value.Neg(value)
+		}
+		return &object.BigInt{Value: value}, nil
+	case tagString:
+		value, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return &object.String{Value: string(value)}, nil
+	case tagCompiledFunction:
+		numLocals, err := readUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		numParameters, err := readUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		instructions, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return &object.CompiledFunction{
+			Instructions:  code.Instructions(instructions),
+			NumLocals:     int(numLocals),
+			NumParameters: int(numParameters),
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid bytecode: unknown constant tag %d", tag)
+	}
+}
+
+func writeUvarint(out *bytes.Buffer, n uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	out.Write(buf[:binary.PutUvarint(buf[:], n)])
+}
+
+func writeBytes(out *bytes.Buffer, b []byte) {
+	writeUvarint(out, uint64(len(b)))
+	out.Write(b)
+}
+
+func readUvarint(r *bytes.Reader) (uint64, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, truncated(err)
+	}
+	return n, nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, truncated(io.ErrUnexpectedEOF)
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return b, nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("invalid bytecode: %s", err)
+}
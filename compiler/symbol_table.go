@@ -1,5 +1,9 @@
 package compiler
 
+import (
+	"sort"
+)
+
 type SymbolScope string
 
 const (
@@ -61,6 +65,38 @@ func (st *SymbolTable) defineFree(original Symbol) Symbol {
 	st.store[original.Name] = sym
 	return sym
 }
+
+// Names returns the sorted names st and the tables around it define.
+func (st *SymbolTable) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for t := st; t != nil; t = t.Outer {
+		for name := range t.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Copy returns a table holding the symbols of st, which can be given more
+// definitions without changing st.
+func (st *SymbolTable) Copy() *SymbolTable {
+	copied := &SymbolTable{
+		Outer:          st.Outer,
+		FreeSymbols:    append([]Symbol{}, st.FreeSymbols...),
+		store:          make(map[string]Symbol, len(st.store)),
+		numDefinitions: st.numDefinitions,
+	}
+	for name, symbol := range st.store {
+		copied.store[name] = symbol
+	}
+	return copied
+}
+
 func (st *SymbolTable) Resolve(name string) (Symbol, bool) {
 	s, ok := st.store[name]
 	if !ok && st.Outer != nil {
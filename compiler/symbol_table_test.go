@@ -1,6 +1,9 @@
 package compiler
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestDefine(t *testing.T) {
 	want := map[string]Symbol{
@@ -302,3 +305,34 @@ func TestResolveBuiltins(t *testing.T) {
 	}
 
 }
+
+func TestNames(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	global.Define("b")
+	local := NewEnclosedSymbolTable(global)
+	local.Define("a")
+	local.Define("b")
+
+	want := []string{"a", "b", "len"}
+	if got := local.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got=%q", want, got)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+	copied := global.Copy()
+	b := copied.Define("b")
+
+	if b.Index != 1 {
+		t.Errorf("b defined at %d, want 1", b.Index)
+	}
+	if ret, ok := copied.Resolve("a"); !ok || ret != a {
+		t.Errorf("want a to resolve to %+v, got=%+v", a, ret)
+	}
+	if _, ok := global.Resolve("b"); ok {
+		t.Errorf("b is defined in the original table")
+	}
+}
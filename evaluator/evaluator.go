@@ -7,9 +7,9 @@ import (
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
@@ -20,6 +20,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.BigIntegerLiteral:
+		return &object.BigInt{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
@@ -29,7 +31,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return evalPrefixExpression(env.Context().Overflow, node.Operator, right)
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
@@ -39,7 +41,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalInfixOperatorExpression(node.Operator, left, right)
+		return evalInfixOperatorExpression(env.Context().Overflow, node.Operator, left, right)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
@@ -59,6 +61,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Function{Parameters: params, Env: env, Body: body}
 	case *ast.CallExpression:
 		if node.Function.TokenLiteral() == "quote" {
+			if len(node.Arguments) != 1 {
+				return newError("wrong number of arguments to quote. got=%d, want=1", len(node.Arguments))
+			}
 			return quote(node.Arguments[0], env)
 		}
 		function := Eval(node.Function, env)
@@ -70,7 +75,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return applyFunction(env.Context(), function, args)
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 
@@ -140,26 +145,26 @@ func nativeBoolToBooleanObject(value bool) *object.Boolean {
 	}
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(overflow object.OverflowPolicy, operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(overflow, right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
-func evalInfixOperatorExpression(operator string,
+func evalInfixOperatorExpression(overflow object.OverflowPolicy, operator string,
 	left object.Object, right object.Object) object.Object {
 	switch {
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+	case object.IsInteger(left) && object.IsInteger(right):
+		return evalIntegerInfixExpression(overflow, operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
@@ -180,38 +185,40 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+func evalMinusPrefixOperatorExpression(overflow object.OverflowPolicy, right object.Object) object.Object {
+	if !object.IsInteger(right) {
 		return newError("unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	result, err := object.Negate(overflow, right)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return result
 }
 
-func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
-
+func evalIntegerInfixExpression(overflow object.OverflowPolicy, operator string, left object.Object, right object.Object) object.Object {
 	switch operator {
-	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
-	case "/":
-		if rightVal == 0 {
-			return newError("zero division error: %d / 0", leftVal)
+	case "+", "-", "*", "/":
+		result, err := object.Arithmetic(overflow, operator, left, right)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
+	case "<", ">", "==", "!=":
+		cmp, err := object.CompareIntegers(left, right)
+		if err != nil {
+			return newError("%s", err)
+		}
+		switch operator {
+		case "<":
+			return nativeBoolToBooleanObject(cmp < 0)
+		case ">":
+			return nativeBoolToBooleanObject(cmp > 0)
+		case "==":
+			return nativeBoolToBooleanObject(cmp == 0)
+		default:
+			return nativeBoolToBooleanObject(cmp != 0)
 		}
-		return &object.Integer{Value: leftVal / rightVal}
-	case "<":
-		return nativeBoolToBooleanObject(leftVal < rightVal)
-	case ">":
-		return nativeBoolToBooleanObject(leftVal > rightVal)
-	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
@@ -248,11 +255,11 @@ func evalIdentifier(node *ast.Identifier,
 		return val
 	}
 
-	if builtin, ok := builtIns[node.Value]; ok {
+	if builtin := object.GetBuiltinName(node.Value); builtin != nil {
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newError("identifier not found: %s", node.Value)
 }
 
 func evalExpressions(
@@ -271,15 +278,23 @@ func evalExpressions(
 	return results
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// Apply calls fn with args, as a call expression in a program would.
+func Apply(ctx *object.Context, fn object.Object, args []object.Object) object.Object {
+	return applyFunction(ctx, fn, args)
+}
+
+func applyFunction(ctx *object.Context, fn object.Object, args []object.Object) object.Object {
 
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+		}
 		extendedEnv := extendFunctionEnv(fn, args)
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		if result := fn.Fn(args...); result != nil {
+		if result := fn.Fn(ctx.Caller(applyFunction), args...); result != nil {
 			return result
 		}
 		return NULL
@@ -292,6 +307,8 @@ func evalIndexExpression(left object.Object, index object.Object) object.Object
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return object.StringIndex(left.(*object.String), index.(*object.Integer).Value)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -313,17 +330,17 @@ func evalArrayIndexExpression(array object.Object, index object.Object) object.O
 func evalHashIndexExpression(hash object.Object, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Get(key)
 
 	if !ok {
 		return NULL
 	}
 
-	return pair.Value
+	return value
 }
 
 func extendFunctionEnv(
@@ -347,16 +364,17 @@ func unwrapReturnValue(obj object.Object) object.Object {
 }
 
 func evalHashLiteral(node *ast.HashLiteral, environment *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.OrderedKeys() {
+		valueNode := node.Pairs[keyNode]
 		key := Eval(keyNode, environment)
 
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
@@ -366,20 +384,17 @@ func evalHashLiteral(node *ast.HashLiteral, environment *object.Environment) obj
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, value)
 	}
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func asTrue(obj object.Object) bool {
-	switch obj {
-	case NULL:
-		return false
-	case TRUE:
-		return true
-	case FALSE:
+	switch obj := obj.(type) {
+	case *object.Null:
 		return false
+	case *object.Boolean:
+		return obj.Value
 	default:
 		return true
 	}
@@ -1,9 +1,11 @@
 package evaluator
 
 import (
+	"bytes"
 	"github.com/masa-suzu/monkey/lexer"
 	"github.com/masa-suzu/monkey/object"
 	"github.com/masa-suzu/monkey/parser"
+	"strings"
 	"testing"
 )
 
@@ -76,6 +78,17 @@ func TestBangOperator(t *testing.T) {
 		{"false != true", true},
 		{"(1 < 2) == true", true},
 		{"(1 < 2) == false", false},
+		{`"monkey" == "mon" + "key"`, true},
+		{`"monkey" != "monkey"`, false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] != [1, 2, 3]", true},
+		{`{"a": [1], 2: true} == {2: true, "a": [1]}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{"fn() { 1 } == fn() { 1 }", false},
+		{"let f = fn() { 1 }; f == f", true},
+		{`[1] == "[1]"`, false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
 	}
@@ -180,7 +193,11 @@ func TestErrorHandling(t *testing.T) {
 		},
 		{
 			"1 / 0;",
-			"zero division error: 1 / 0",
+			"integer divide by zero",
+		},
+		{
+			"123456789012345678901234567890 / (5 - 5);",
+			"integer divide by zero",
 		},
 
 		{
@@ -203,6 +220,14 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{[fn(x) { x }]: 1};`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			"fn(x, y) { x + y }(1);",
+			"wrong number of arguments: want=2, got=1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -360,8 +385,6 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`puts(1)`, NULL},
 		{`puts("1")`, NULL},
 		{`puts("1 +1")`, NULL},
-		{`help()`, NULL},
-		{`help(1)`, NULL},
 	}
 
 	for _, tt := range tests {
@@ -387,6 +410,383 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestBuiltinFunctionsWithContext(t *testing.T) {
+	tests := []struct {
+		input    string
+		stdin    string
+		expected interface{}
+		out      string
+	}{
+		{`puts("monkey")`, "", nil, "monkey\n"},
+		{`puts(1, [2, 3])`, "", nil, "1\n[2, 3]\n"},
+		{`gets()`, "monkey\nbusiness\n", "monkey", ""},
+		{`gets(); gets()`, "monkey\nbusiness", "business", ""},
+		{`gets()`, "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		ctx := object.NewContext(strings.NewReader(tt.stdin), out)
+		env := object.NewEnvironmentWithContext(ctx)
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := Eval(program, env)
+
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q", str.Value)
+			}
+		}
+		if out.String() != tt.out {
+			t.Errorf("%s wrote wrong output. expected=%q, got=%q", tt.input, tt.out, out.String())
+		}
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_parse("1")`, "1"},
+		{`json_parse("-12")`, "-12"},
+		{`json_parse("1e3")`, "1000"},
+		{`json_parse("[1, \"two\", true, null, []]")`, "[1, two, true, null, []]"},
+		{`json_parse("{\"a\": {\"b\": [1]}}")["a"]["b"][0]`, "1"},
+		{`json_parse("1.5")`, "ERROR: json_parse: number 1.5 is not an integer"},
+		{`json_parse("1e30")`, "1000000000000000000000000000000"},
+		{`type(json_parse("123456789012345678901234"))`, "INTEGER"},
+		{`json_parse("-1.2e19")`, "-12000000000000000000"},
+		{`json_parse("2.50e1")`, "25"},
+		{`json_parse("1e-3")`, "ERROR: json_parse: number 1e-3 is not an integer"},
+		{`json_parse("1e100000")`, "ERROR: json_parse: exponent of number 1e100000 is out of range"},
+		{`json_parse("[1,")`, "ERROR: json_parse: unexpected end of JSON input"},
+		{`json_parse("1 2")`, "ERROR: json_parse: unexpected data after top-level value"},
+		{`json_parse(1)`, "ERROR: argument to `json_parse` must be STRING, got INTEGER"},
+		{`json_stringify(1)`, "1"},
+		{`json_stringify("a\"<b>")`, `"a\"<b>"`},
+		{`json_stringify([1, "two", false, if (false) { 1 }])`, `[1,"two",false,null]`},
+		{`json_stringify({"b": 2, "a": [1], 3: true, false: 0})`, `{"b":2,"a":[1],"3":true,"false":0}`},
+		{`json_stringify(json_parse("{\"z\": 1, \"a\": 2, \"m\": 3}"))`, `{"z":1,"a":2,"m":3}`},
+		{`json_stringify({"a": [1, 2]}, 2)`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}"},
+		{`json_stringify([1], "\t")`, "[\n\t1\n]"},
+		{`json_stringify(json_parse("{\"k\": [1, {\"n\": null}]}"))`, `{"k":[1,{"n":null}]}`},
+		{`json_stringify({1: "a", "1": "b"})`, `ERROR: json_stringify: duplicate object key "1"`},
+		{`json_stringify({true: 1, "x": 2}, 1)`, "{\n \"true\": 1,\n \"x\": 2\n}"},
+		{`json_stringify(fn(x) { x })`, "ERROR: json_stringify: FUNCTION can not be encoded"},
+		{`json_stringify({"f": len})`, "ERROR: json_stringify: BUILTIN can not be encoded"},
+		{`json_stringify(1, true)`, "ERROR: indent to `json_stringify` must be INTEGER or STRING, got BOOLEAN"},
+		{`json_stringify()`, "ERROR: wrong number of arguments. got=0, want=1 or 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = [1, 2]; push(a, 3); a`, "[1, 2]"},
+		{`push([1, 2], [3])`, "[1, 2, [3]]"},
+		{`push(1, 2)`, "ERROR: argument to `push` must be ARRAY, got INTEGER"},
+		{`pop([1, 2, 3])`, "3"},
+		{`pop([])`, "null"},
+		{`let a = [1, 2]; pop(a); a`, "[1, 2]"},
+		{`pop(1)`, "ERROR: argument to `pop` must be ARRAY, got INTEGER"},
+		{`concat([1], [], [2, 3])`, "[1, 2, 3]"},
+		{`concat()`, "[]"},
+		{`slice([1, 2, 3, 4], 1)`, "[2, 3, 4]"},
+		{`slice([1, 2, 3, 4], 1, 3)`, "[2, 3]"},
+		{`slice([1, 2, 3, 4], -2)`, "[3, 4]"},
+		{`slice([1, 2, 3, 4], 3, 1)`, "[]"},
+		{`slice([1, 2, 3, 4], -10, 10)`, "[1, 2, 3, 4]"},
+		{`slice([1], "a")`, "ERROR: argument to `slice` must be INTEGER, got STRING"},
+		{`reverse([1, 2, 3])`, "[3, 2, 1]"},
+		{`sort([3, 1, 2])`, "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{`sort([pow(2, 70), 1, -pow(2, 64)])`, "[-18446744073709551616, 1, 1180591620717411303424]"},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, "[3, 2, 1]"},
+		{`sort([[2, "b"], [1, "a"]], fn(a, b) { a[0] < b[0] })`, "[[1, a], [2, b]]"},
+		{`sort([1, "a"])`, "ERROR: `sort` can not compare STRING and INTEGER"},
+		{`sort([1, 2], fn(a, b) { 1 })`, "ERROR: comparator of `sort` must return BOOLEAN, got INTEGER"},
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map([[1], [2, 3]], len)`, "[1, 2]"},
+		{`let n = 10; map([1, 2], fn(x) { x + n })`, "[11, 12]"},
+		{`map([1, 2], fn(x, y) { x })`, "ERROR: wrong number of arguments: want=2, got=1"},
+		{`map([1, 2], 1)`, "ERROR: argument to `map` must be a function, got INTEGER"},
+		{`map([1, 2], fn(x) { x + true })`, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, "[3, 4]"},
+		{`reduce([1, 2, 3], 0, fn(acc, x) { acc + x })`, "6"},
+		{`reduce([], 10, fn(acc, x) { acc + x })`, "10"},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, "2"},
+		{`find([1, 2, 3], fn(x) { x > 3 })`, "null"},
+		{`any([1, 2, 3], fn(x) { x > 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, "false"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`zip()`, "ERROR: wrong number of arguments. got=0, want at least 1"},
+		{`range(3)`, "[0, 1, 2]"},
+		{`range(1, 4)`, "[1, 2, 3]"},
+		{`range(5, 0, -2)`, "[5, 3, 1]"},
+		{`range(0, 1, 0)`, "ERROR: step of `range` must not be 0"},
+		{`range(3, 1)`, "[]"},
+		{`range(9223372036854775806, 9223372036854775807, 2)`, "[9223372036854775806]"},
+		{`range(-9223372036854775807 - 1, -9223372036854775807, -1)`, "[]"},
+		{`range(-9223372036854775806, -9223372036854775807 - 1, -9223372036854775807 - 1)`, "[-9223372036854775806]"},
+		{`range(-9223372036854775807 - 1, 9223372036854775807, 4611686018427387904)`, "[-9223372036854775808, -4611686018427387904, 0, 4611686018427387904]"},
+		{`range(-9223372036854775807 - 1, 9223372036854775807)`, "ERROR: `range` would return more than 16777216 elements"},
+		{`flatten([1, [2, [3]], []])`, "[1, 2, [3]]"},
+		{`unique([1, 2, 1, [1], [1], "1"])`, "[1, 2, [1], 1]"},
+		{`let f = fn() { 1 }; len(unique([f, f, fn() { 1 }]))`, "2"},
+		{`reduce(map(range(1, 6), fn(x) { x * x }), 0, fn(a, b) { a + b })`, "55"},
+		{`map([[3, 1], [2]], fn(a) { sort(a, fn(x, y) { x < y }) })`, "[[1, 3], [2]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("héllo")`, "5"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[4]`, "o"},
+		{`"héllo"[5]`, "null"},
+		{`"héllo"[-1]`, "null"},
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`split("añb", "")`, "[a, ñ, b]"},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([], "-")`, ""},
+		{`join(["a", 1], "-")`, "ERROR: elements of `join` must be STRING, got INTEGER"},
+		{`trim("  monkey \n")`, "monkey"},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("MONKEY")`, "monkey"},
+		{`contains("monkey", "key")`, "true"},
+		{`contains("monkey", "Key")`, "false"},
+		{`index_of("añbñ", "b")`, "2"},
+		{`index_of("monkey", "z")`, "-1"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`starts_with("monkey", "mon")`, "true"},
+		{`ends_with("monkey", "mon")`, "false"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", -1)`, "ERROR: count of `repeat` must not be negative, got -1"},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("héllo", -2)`, "lo"},
+		{`substr("héllo", 3, 10)`, "lo"},
+		{`substr("héllo", 10)`, ""},
+		{`substr("abc", 1, 9223372036854775807)`, "bc"},
+		{`repeat("ab", 9223372036854775807)`, "ERROR: result of `repeat` would be longer than 1073741824 bytes"},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`chars("añb")`, "[a, ñ, b]"},
+		{`ord("ñ")`, "241"},
+		{`ord("ab")`, `ERROR: argument to ` + "`ord`" + ` must be a single character, got "ab"`},
+		{`chr(241)`, "ñ"},
+		{`chr(-1)`, "ERROR: -1 is not a valid character code"},
+		{`format("%s is %d years old", "Monkey", 3)`, "Monkey is 3 years old"},
+		{`format("%5d|%-4s|%x|%t|%q|%v", 42, "ab", 255, true, "m", [1, "a"])`, `   42|ab  |ff|true|"m"|[1, a]`},
+		{`format("100%%")`, "100%"},
+		{`format("%d", "a")`, "ERROR: format: %d wants INTEGER, got STRING"},
+		{`format("%s %s", "a")`, "ERROR: format: missing argument for %s"},
+		{`format("%s", "a", "b")`, "ERROR: format: 1 arguments left over"},
+		{`format("%y", 1)`, "ERROR: format: unknown verb %y"},
+		{`upper(1)`, "ERROR: argument to `upper` must be STRING, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys({"b": 1, "a": 2, 3: 4})`, "[b, a, 3]"},
+		{`values({"b": 1, "a": 2, 3: 4})`, "[1, 2, 4]"},
+		{`entries({"b": 1, [1]: 2})`, "[[b, 1], [[1], 2]]"},
+		{`keys({})`, "[]"},
+		{`has({"a": 1}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`has({"a": 1}, fn() {})`, "ERROR: unusable as hash key: FUNCTION"},
+		{`get({"a": 1}, "a")`, "1"},
+		{`get({"a": 1}, "b")`, "null"},
+		{`get({"a": 1}, "b", 0)`, "0"},
+		{`let h = {"a": 1, "b": 2, "c": 3}; delete(h, "b")`, "{a: 1, c: 3}"},
+		{`let h = {"a": 1, "b": 2}; delete(h, "a"); h`, "{a: 1, b: 2}"},
+		{`delete({"a": 1}, "z")`, "{a: 1}"},
+		{`merge({"a": 1, "b": 2}, {"b": 3, "c": 4}, {"a": 5})`, "{a: 5, b: 3, c: 4}"},
+		{`let h = {"a": 1}; merge(h, {"a": 2}); h`, "{a: 1}"},
+		{`merge({"a": 1}, 1)`, "ERROR: argument to `merge` must be HASH, got INTEGER"},
+		{`from_entries([["b", 1], ["a", 2], ["b", 3]])`, "{b: 3, a: 2}"},
+		{`from_entries(entries({"x": 1, 2: true}))`, "{x: 1, 2: true}"},
+		{`from_entries([["a"]])`, "ERROR: entries of `from_entries` must be [key, value] arrays, got [a]"},
+		{`keys(1)`, "ERROR: argument to `keys` must be HASH, got INTEGER"},
+		{`from_entries(map(range(3), fn(i) { [i, i * i] }))`, "{0: 0, 1: 1, 2: 4}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`abs(-5)`, "5"},
+		{`abs(5)`, "5"},
+		{`min(3, 1, 2)`, "1"},
+		{`max(3, 1, 2)`, "3"},
+		{`max([4, 9, 2])`, "9"},
+		{`min([])`, "ERROR: `min` needs at least one integer"},
+		{`max(1, "a")`, "ERROR: argument to `max` must be INTEGER, got STRING"},
+		{`pow(2, 10)`, "1024"},
+		{`pow(-3, 3)`, "-27"},
+		{`pow(5, 0)`, "1"},
+		{`pow(2, -1)`, "ERROR: exponent of `pow` must not be negative, got -1"},
+		{`pow(2, 100000000000)`, "ERROR: `pow` would return more than 16777216 bits"},
+		{`pow(pow(2, 70), 300000)`, "ERROR: `pow` would return more than 16777216 bits"},
+		{`pow(1, 100000000000)`, "1"},
+		{`pow(-1, 100000000001)`, "-1"},
+		{`pow(0, 100000000000)`, "0"},
+		{`sqrt(17)`, "4"},
+		{`sqrt(-1)`, "ERROR: argument to `sqrt` must not be negative, got -1"},
+		{`floor(7, 2)`, "3"},
+		{`floor(-7, 2)`, "-4"},
+		{`ceil(7, 2)`, "4"},
+		{`ceil(-7, 2)`, "-3"},
+		{`ceil(6, 2)`, "3"},
+		{`floor(1, 0)`, "ERROR: integer divide by zero"},
+		{`modulo(7, 3)`, "1"},
+		{`modulo(-7, 3)`, "2"},
+		{`modulo(7, -3)`, "-2"},
+		{`clamp(5, 0, 3)`, "3"},
+		{`clamp(-5, 0, 3)`, "0"},
+		{`clamp(2, 0, 3)`, "2"},
+		{`clamp(2, 3, 0)`, "ERROR: bounds of `clamp` are reversed: 3 > 0"},
+		{`gcd(12, -18)`, "6"},
+		{`gcd(0, 0)`, "0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`123456789012345678901234567890`, "123456789012345678901234567890"},
+		{`123456789012345678901234567890 + 1`, "123456789012345678901234567891"},
+		{`-9223372036854775808`, "-9223372036854775808"},
+		{`9223372036854775808 - 1`, "9223372036854775807"},
+		{`let x = 9223372036854775807; x * x`, "85070591730234615847396907784232501249"},
+		{`(9223372036854775807 + 1) / 2`, "4611686018427387904"},
+		{`9223372036854775808 > 1`, "true"},
+		{`1 < -9223372036854775809`, "false"},
+		{`9223372036854775808 == 9223372036854775807 + 1`, "true"},
+		{`9223372036854775808 != 9223372036854775808`, "false"},
+		{`[9223372036854775808] == [9223372036854775807 + 1]`, "true"},
+		{`{9223372036854775808: "big"}[9223372036854775807 + 1]`, "big"},
+		{`{9223372036854775808 - 1: "small"}[9223372036854775807]`, "small"},
+		{`json_stringify([18446744073709551616])`, "[18446744073709551616]"},
+		{`json_parse("18446744073709551616") - 1`, "18446744073709551615"},
+		{`gcd(18446744073709551616, 12)`, "4"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTypeBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type(1)`, "INTEGER"},
+		{`type(9223372036854775808)`, "INTEGER"},
+		{`type("a")`, "STRING"},
+		{`type(true)`, "BOOLEAN"},
+		{`type(if (false) { 1 })`, "NULL"},
+		{`type([])`, "ARRAY"},
+		{`type({})`, "HASH"},
+		{`type(fn(x) { x })`, "FUNCTION"},
+		{`type(len)`, "BUILTIN"},
+		{`type(quote(1 + 2))`, "QUOTE"},
+		{`is_int(1)`, "true"},
+		{`is_int("1")`, "false"},
+		{`is_string("1")`, "true"},
+		{`is_bool(false)`, "true"},
+		{`is_null(puts())`, "true"},
+		{`is_array([1])`, "true"},
+		{`is_hash({})`, "true"},
+		{`is_fn(fn() {})`, "true"},
+		{`is_fn(len)`, "true"},
+		{`is_fn(1)`, "false"},
+		{`str(42)`, "42"},
+		{`str([1, "a"]) + "!"`, "[1, a]!"},
+		{`str("a")`, "a"},
+		{`int("42") + 1`, "43"},
+		{`int(" -7 ")`, "-7"},
+		{`int("123456789012345678901234567890")`, "123456789012345678901234567890"},
+		{`int(true) + int(false)`, "1"},
+		{`int(5)`, "5"},
+		{`int("4x")`, `ERROR: could not convert "4x" to INTEGER`},
+		{`int([])`, "ERROR: argument to `int` must be INTEGER, STRING or BOOLEAN, got ARRAY"},
+		{`arity(fn(a, b) { a })`, "2"},
+		{`arity(fn() { 1 })`, "0"},
+		{`arity(len)`, "1"},
+		{`arity(reduce)`, "3"},
+		{`arity(puts)`, "-1"},
+		{`arity(1)`, "ERROR: argument to `arity` must be a function, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -481,26 +881,52 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for i, tt := range expected {
+		value, ok := result.Get(tt.key)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
 
-		testIntegerObject(t, pair.Value, expectedValue)
+		testIntegerObject(t, value, tt.value)
+
+		if result.Pairs()[i].Key.Inspect() != tt.key.Inspect() {
+			t.Errorf("pair %d has wrong key. expected=%s, got=%s", i, tt.key.Inspect(), result.Pairs()[i].Key.Inspect())
+		}
+	}
+}
+
+func TestHashInspectOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{`{3: "c", 1: "a", 2: "b"}`, "{3: c, 1: a, 2: b}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a: 3, b: 2}"},
+		{`{true: 1, false: 0}`, "{true: 1, false: 0}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
 	}
 }
 
@@ -537,6 +963,14 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{[1, [2]]: 5}[[1, [2]]]`,
+			5,
+		},
+		{
+			`{[1, 2]: 5}[[2, 1]]`,
+			nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -576,6 +1010,7 @@ func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	env := object.NewEnvironment()
+	ctx := object.NewContext(strings.NewReader(""), &bytes.Buffer{})
+	env := object.NewEnvironmentWithContext(ctx)
 	return Eval(program, env)
 }
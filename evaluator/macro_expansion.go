@@ -1,8 +1,10 @@
 package evaluator
 
 import (
+	"fmt"
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/object"
+	"io"
 )
 
 func DefineMacros(program *ast.Program, env *object.Environment) {
@@ -51,10 +53,43 @@ func addMacro(stmt ast.Statement, env *object.Environment) {
 	env.Set(letStatement.Name.Value, macro)
 }
 
-func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
-	return ast.Modify(program, func(node ast.Node) ast.Node {
+// Expand defines the macros of program in macros and expands every macro call
+// in what is left. It is the front end shared by the evaluator and the
+// compiler, so both backends see the same program, and macros defined by one
+// program remain usable by the next one expanded with the same environment.
+func Expand(program *ast.Program, macros *object.Environment) (*ast.Program, error) {
+	DefineMacros(program, macros)
+	expanded, err := expandMacros(program, macros, 0)
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(*ast.Program), nil
+}
+
+// MacroExpander returns the expander of the macroexpand builtins for the
+// macros defined in env. Nodes are copied before they are expanded.
+func MacroExpander(env *object.Environment) object.MacroExpander {
+	return func(node ast.Node, all bool) (ast.Node, error) {
+		node = ast.Copy(node)
+		if all {
+			return expandMacros(node, env, 0)
+		}
+		return expandOnce(node, env)
+	}
+}
+
+// maxExpansionDepth limits how deeply expansions may expand to further macro
+// calls, to stop macros which expand to calls of themselves.
+const maxExpansionDepth = 1000
+
+// expandMacros expands every macro call in node, including those expansions
+// contain. Calls within quote are left alone.
+func expandMacros(node ast.Node, env *object.Environment, depth int) (ast.Node, error) {
+	quoted := quotedCalls(node)
+	var err error
+	expanded, modifyErr := ast.Modify(node, func(node ast.Node) ast.Node {
 		callExpression, ok := node.(*ast.CallExpression)
-		if !ok {
+		if !ok || err != nil || quoted[callExpression] {
 			return node
 		}
 
@@ -62,18 +97,157 @@ func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
 		if !ok {
 			return node
 		}
+		if depth >= maxExpansionDepth {
+			err = fmt.Errorf("macro expansion is too deep at %s", callExpression)
+			return node
+		}
 
-		args := quoteArgs(callExpression)
-		evalEnv := extendMacroEnv(macro, args)
+		var expansion ast.Node
+		expansion, err = expandCall(callExpression, macro, env)
+		if err != nil {
+			return node
+		}
+		expansion, err = expandMacros(expansion, env, depth+1)
+		if err != nil {
+			return node
+		}
+		return expansion
+	})
+	if err == nil {
+		err = modifyErr
+	}
+	return expanded, err
+}
 
-		evaluated := Eval(macro.Body, evalEnv)
+// expandOnce expands node if it is a macro call, leaving the calls its
+// arguments and expansion contain alone.
+func expandOnce(node ast.Node, env *object.Environment) (ast.Node, error) {
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return node, nil
+	}
+	macro, ok := isMacroCall(callExpression, env)
+	if !ok {
+		return node, nil
+	}
+	return expandCall(callExpression, macro, env)
+}
 
-		quote, ok := evaluated.(*object.Quote)
-		if !ok {
-			panic("we only support returning AST-nodes from macros")
+// quotedCalls returns the calls within the arguments of quote calls.
+func quotedCalls(node ast.Node) map[*ast.CallExpression]bool {
+	quoted := map[*ast.CallExpression]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpression)
+		if !ok || call.Function == nil || call.Function.TokenLiteral() != "quote" {
+			return true
+		}
+		for _, arg := range call.Arguments {
+			ast.Inspect(arg, func(n ast.Node) bool {
+				if c, ok := n.(*ast.CallExpression); ok {
+					quoted[c] = true
+				}
+				return true
+			})
+		}
+		return false
+	})
+	return quoted
+}
+
+func expandCall(callExpression *ast.CallExpression, macro *object.Macro, env *object.Environment) (ast.Node, error) {
+	if len(callExpression.Arguments) != len(macro.Parameters) {
+		return nil, fmt.Errorf("wrong number of arguments to macro %s: want=%d, got=%d",
+			callExpression.Function, len(macro.Parameters), len(callExpression.Arguments))
+	}
+
+	args := quoteArgs(callExpression)
+	evalEnv := extendMacroEnv(macro, args)
+
+	var evaluated object.Object
+	evalEnv.Context().ExpandMacro(func() {
+		evaluated = Eval(macro.Body, evalEnv)
+	})
+	if returnValue, ok := evaluated.(*object.ReturnValue); ok {
+		evaluated = returnValue.Value
+	}
+
+	var expansion ast.Node
+	switch evaluated := evaluated.(type) {
+	case *object.Quote:
+		expansion = evaluated.Node
+	case *object.Error:
+		return nil, fmt.Errorf("in macro %s: %s", callExpression.Function, evaluated.Message)
+	case nil:
+		return nil, fmt.Errorf("macro %s must return a quote, got nothing", callExpression.Function)
+	default:
+		return nil, fmt.Errorf("macro %s must return a quote, got %s", callExpression.Function, evaluated.Type())
+	}
+
+	ctx := env.Context()
+	if ctx.HygienicMacros {
+		var err error
+		expansion, err = renameBindings(expansion, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ctx.MacroTrace != nil {
+		traceExpansion(ctx.MacroTrace, callExpression, macro, expansion)
+	}
+	return expansion, nil
+}
+
+// traceExpansion writes a macro call, its arguments and its expansion to w.
+func traceExpansion(w io.Writer, call *ast.CallExpression, macro *object.Macro, expansion ast.Node) {
+	fmt.Fprintf(w, "expand %s\n", call)
+	for i, param := range macro.Parameters {
+		fmt.Fprintf(w, "\t%s: %s\n", param, call.Arguments[i])
+	}
+	fmt.Fprintf(w, "\t=> %s\n", expansion)
+}
+
+// renameBindings gives the identifiers an expansion binds with let and fn
+// fresh names, leaving the arguments of the macro call alone. The expansion
+// then neither captures names of the arguments nor shadows names around the
+// call site.
+func renameBindings(expansion ast.Node, args []*object.Quote) (ast.Node, error) {
+	fromArgs := map[*ast.Identifier]bool{}
+	for _, arg := range args {
+		for identifier := range identifiersIn(arg.Node) {
+			fromArgs[identifier] = true
+		}
+	}
+
+	renamed := map[string]*ast.Identifier{}
+	bind := func(identifier *ast.Identifier) {
+		if identifier == nil || fromArgs[identifier] || object.IsGensym(identifier) {
+			return
 		}
+		if _, ok := renamed[identifier.Value]; !ok {
+			renamed[identifier.Value] = object.Gensym(identifier.Value)
+		}
+	}
+	ast.Inspect(expansion, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			bind(node.Name)
+		case *ast.FunctionLiteral:
+			for _, parameter := range node.Parameters {
+				bind(parameter)
+			}
+		}
+		return true
+	})
 
-		return quote.Node
+	return ast.Modify(expansion, func(node ast.Node) ast.Node {
+		identifier, ok := node.(*ast.Identifier)
+		if !ok || fromArgs[identifier] {
+			return node
+		}
+		if gensym, ok := renamed[identifier.Value]; ok {
+			return &ast.Identifier{Token: gensym.Token, Value: gensym.Value}
+		}
+		return node
 	})
 }
 
@@ -1,10 +1,12 @@
 package evaluator
 
 import (
+	"bytes"
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/lexer"
 	"github.com/masa-suzu/monkey/object"
 	"github.com/masa-suzu/monkey/parser"
+	"strings"
 	"testing"
 )
 
@@ -104,15 +106,33 @@ func TestExpandMacros(t *testing.T) {
             `,
 			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
 		},
+		{
+			`
+            let infixExpression = macro() { quote(1 + 2); };
+
+            puts(infixExpression(), [infixExpression()]);
+            `,
+			`puts((1 + 2), [(1 + 2)])`,
+		},
+		{
+			`
+            let threeTimes = macro(x) { quote(fn() { unquote_splicing([x, x, x]) }()) };
+
+            threeTimes(puts("hi"));
+            `,
+			`fn() { puts("hi"); puts("hi"); puts("hi") }()`,
+		},
 	}
 
 	for _, tt := range tests {
 		expected := testParseProgram(tt.expected)
 		program := testParseProgram(tt.input)
 
-		env := object.NewEnvironment()
-		DefineMacros(program, env)
-		expanded := ExpandMacros(program, env)
+		expanded, err := Expand(program, object.NewEnvironment())
+		if err != nil {
+			t.Errorf("%q got error: %s", tt.input, err)
+			continue
+		}
 
 		if expanded.String() != expected.String() {
 			t.Errorf("not equal. want=%q, got=%q",
@@ -120,3 +140,252 @@ func TestExpandMacros(t *testing.T) {
 		}
 	}
 }
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let m = macro() { 1 }; m();`,
+			"macro m must return a quote, got INTEGER",
+		},
+		{
+			`let m = macro(a) { quote(unquote(a)) }; m();`,
+			"wrong number of arguments to macro m: want=1, got=0",
+		},
+		{
+			`let m = macro() { len(1) }; m();`,
+			"in macro m: argument to `len` not supported, got INTEGER",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Expand(testParseProgram(tt.input), object.NewEnvironment())
+		if err == nil {
+			t.Errorf("%q expected an error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+const (
+	swapMacro = `let swap = macro(a, b) { quote(fn() { let tmp = unquote(a); [unquote(b), tmp] }()) };`
+
+	unlessMacro = `let unless = macro(cond, body) {
+		quote(fn() { let result = !(unquote(cond)); if (result) { unquote(body) } }())
+	};`
+)
+
+func TestMacroCapture(t *testing.T) {
+	tests := []struct {
+		input    string
+		hygienic bool
+		expected string
+	}{
+		{swapMacro + `let x = 1; let y = 2; swap(x, y)`, false, "[2, 1]"},
+		// Without hygiene the tmp of swap captures the tmp of the caller.
+		{swapMacro + `let y = 1; let tmp = 2; swap(y, tmp)`, false, "[1, 1]"},
+		{swapMacro + `let tmp = 1; let y = 2; swap(tmp, y)`, true, "[2, 1]"},
+		{swapMacro + `let y = 1; let tmp = 2; swap(y, tmp)`, true, "[2, 1]"},
+		{unlessMacro + `let result = 5; unless(false, result)`, false, "true"},
+		{unlessMacro + `let result = 5; unless(false, result)`, true, "5"},
+		{unlessMacro + `let result = 5; unless(true, result)`, true, "null"},
+		// gensym gives names which can not be captured even without hygiene.
+		{
+			`let swap = macro(a, b) {
+				let tmp = gensym("tmp");
+				quote(fn() { let tmp = unquote(a); [unquote(b), tmp] }())
+			};
+			let y = 1; let tmp = 2; swap(y, tmp)`,
+			false,
+			"[2, 1]",
+		},
+		{
+			`let twice = macro(x) { let v = gensym(); quote(fn(v) { v + v }(unquote(x))) };
+			let v = 3; twice(v * 2)`,
+			false,
+			"12",
+		},
+		// Expanding a macro again does not reuse the arguments of earlier calls.
+		{`let inc = macro(x) { quote(unquote(x) + 1) }; [inc(1), inc(10)]`, false, "[2, 11]"},
+		{`let inc = macro(x) { quote(unquote(x) + 1) }; [inc(1), inc(10)]`, true, "[2, 11]"},
+	}
+
+	for _, tt := range tests {
+		ctx := object.NewContext(strings.NewReader(""), &bytes.Buffer{})
+		ctx.HygienicMacros = tt.hygienic
+		macros := object.NewEnvironmentWithContext(ctx)
+
+		expanded, err := Expand(testParseProgram(tt.input), macros)
+		if err != nil {
+			t.Errorf("%q got error: %s", tt.input, err)
+			continue
+		}
+		evaluated := Eval(expanded, object.NewEnvironmentWithContext(ctx))
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("hygienic=%t: %q expected=%s, got=%s", tt.hygienic, tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHygienicExpansion(t *testing.T) {
+	ctx := object.NewStdContext()
+	ctx.HygienicMacros = true
+	macros := object.NewEnvironmentWithContext(ctx)
+
+	program := testParseProgram(swapMacro + `swap(tmp, 1)`)
+	expanded, err := Expand(program, macros)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	got := expanded.String()
+	if strings.Contains(got, "let tmp =") || !strings.Contains(got, "let tmp#") {
+		t.Errorf("tmp of swap is not renamed: %s", got)
+	}
+	if !strings.Contains(got, "[1, tmp#") || !strings.Contains(got, "= tmp;") {
+		t.Errorf("the arguments of swap are renamed: %s", got)
+	}
+}
+
+func TestGensym(t *testing.T) {
+	a := object.Gensym("tmp")
+	b := object.Gensym("tmp")
+	if a.Value == b.Value {
+		t.Errorf("gensyms are not unique: %s", a.Value)
+	}
+	if !strings.HasPrefix(a.Value, "tmp#") || !object.IsGensym(a) {
+		t.Errorf("wrong gensym: %s", a.Value)
+	}
+	if object.IsGensym(&ast.Identifier{Value: "tmp"}) {
+		t.Errorf("tmp is reported as a gensym")
+	}
+
+	evaluated := testEval(`gensym("x")`)
+	q, ok := evaluated.(*object.Quote)
+	if !ok {
+		t.Fatalf("object is not Quote. got=%T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(q.Node.String(), "x#") {
+		t.Errorf("wrong gensym: %s", q.Node.String())
+	}
+}
+
+func TestReadableGensyms(t *testing.T) {
+	ctx := object.NewStdContext()
+	ctx.HygienicMacros = true
+	macros := object.NewEnvironmentWithContext(ctx)
+
+	input := swapMacro + `let twice = macro(x) { let v = gensym("a b"); quote(fn(v) { v + v }(unquote(x))) };
+	let tmp_1 = 1; let y = 2; [swap(tmp_1, y), twice(tmp_1)]`
+	expanded, err := Expand(testParseProgram(input), macros)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	readable := object.ReadableGensyms(expanded)
+	printed := readable.String()
+	if strings.Contains(printed, "#") || !strings.Contains(printed, "let tmp_2 =") || !strings.Contains(printed, "fn(g_1)") {
+		t.Fatalf("gensyms are not renamed: %s", printed)
+	}
+	if !strings.Contains(expanded.String(), "#") {
+		t.Errorf("the expansion is changed: %s", expanded.String())
+	}
+
+	reparsed := testParseProgram(printed)
+	evaluated := Eval(reparsed, object.NewEnvironmentWithContext(ctx))
+	if evaluated.Inspect() != "[[2, 1], 2]" {
+		t.Errorf("wrong value of %s. got=%s", printed, evaluated.Inspect())
+	}
+}
+
+func TestMacroExpandBuiltins(t *testing.T) {
+	macros := `
+	let twice = macro(x) { quote(unquote(x) + unquote(x)) };
+	let four = macro(x) { quote(twice(twice(unquote(x)))) };
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`macroexpand(quote(four(1)))`, `QUOTE(((1 + 1) + (1 + 1)))`},
+		{`macroexpand_1(quote(four(1)))`, `QUOTE(twice(twice(1)))`},
+		{`macroexpand_1(macroexpand_1(quote(four(1))))`, `QUOTE((twice(1) + twice(1)))`},
+		{`macroexpand_1(quote(f(twice(1))))`, `QUOTE(f(twice(1)))`},
+		{`macroexpand(quote(f(twice(1))))`, `QUOTE(f((1 + 1)))`},
+		{`let q = quote(twice(1)); macroexpand(q); q`, `QUOTE(twice(1))`},
+		{`quote(twice(1))`, `QUOTE(twice(1))`},
+		{`macroexpand(1)`, "ERROR: argument to `macroexpand` must be QUOTE, got INTEGER"},
+		{`macroexpand_1()`, "ERROR: wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		ctx := object.NewContext(strings.NewReader(""), &bytes.Buffer{})
+		env := object.NewEnvironmentWithContext(ctx)
+		ctx.Macros = MacroExpander(env)
+
+		expanded, err := Expand(testParseProgram(macros+tt.input), env)
+		if err != nil {
+			t.Errorf("%q got error: %s", tt.input, err)
+			continue
+		}
+		evaluated := Eval(expanded, object.NewEnvironmentWithContext(ctx))
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestExpandNested(t *testing.T) {
+	input := `
+	let twice = macro(x) { quote(unquote(x) + unquote(x)) };
+	let four = macro(x) { quote(twice(twice(unquote(x)))) };
+	four(1);
+	`
+	expanded, err := Expand(testParseProgram(input), object.NewEnvironment())
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if expanded.String() != testParseProgram("((1 + 1) + (1 + 1))").String() {
+		t.Errorf("wrong expansion: %s", expanded.String())
+	}
+
+	_, err = Expand(testParseProgram(`let loop = macro() { quote(loop()) }; loop();`), object.NewEnvironment())
+	if err == nil || err.Error() != "macro expansion is too deep at loop()" {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestMacroTrace(t *testing.T) {
+	trace := &bytes.Buffer{}
+	ctx := object.NewContext(strings.NewReader(""), &bytes.Buffer{})
+	ctx.MacroTrace = trace
+
+	input := `
+	let twice = macro(x) { quote(unquote(x) + unquote(x)) };
+	let four = macro(x) { quote(twice(twice(unquote(x)))) };
+	four(1);
+	`
+	if _, err := Expand(testParseProgram(input), object.NewEnvironmentWithContext(ctx)); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	expected := `expand four(1)
+	x: 1
+	=> twice(twice(1))
+expand twice(1)
+	x: 1
+	=> (1 + 1)
+expand twice((1 + 1))
+	x: (1 + 1)
+	=> ((1 + 1) + (1 + 1))
+`
+	if trace.String() != expected {
+		t.Errorf("wrong trace.\nwant=%s\ngot=%s", expected, trace.String())
+	}
+}
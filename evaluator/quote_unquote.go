@@ -5,31 +5,215 @@ import (
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/object"
 	"github.com/masa-suzu/monkey/token"
+	"strings"
 )
 
-func quote(node ast.Node, env *object.Environment) *object.Quote {
-	node = evalUnquoteCalls(node, env)
+func quote(node ast.Node, env *object.Environment) object.Object {
+	// The quoted node belongs to a function or macro body that may run
+	// again, so unquote calls are replaced in a copy of it.
+	node, unquoted, err := evalUnquoteCalls(ast.Copy(node), env)
+	if err != nil {
+		return newError("%s", err)
+	}
+	if env.Context().ExpandingMacro() {
+		node, err = substituteGensyms(node, env, identifiersIn(unquoted...))
+		if err != nil {
+			return newError("%s", err)
+		}
+	}
 	return &object.Quote{Node: node}
 }
-func evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
+
+// evalUnquoteCalls replaces unquote calls with the nodes of their values and
+// splices the elements of unquote_splicing calls into the lists holding them.
+// It returns the nodes it put in as well.
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, []ast.Node, error) {
+	u := &unquoter{env: env, unquoted: []ast.Node{}}
+	node, err := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if u.err != nil {
+			return node
+		}
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			if isUnquoteCall(node) {
+				return u.unquote(node)
+			}
+			node.Arguments = u.spliceExpressions(node.Arguments)
+		case *ast.ArrayLiteral:
+			node.Elements = u.spliceExpressions(node.Elements)
+		case *ast.BlockStatement:
+			node.Statements = u.spliceStatements(node.Statements)
+		}
+		return node
+	})
+	if u.err != nil {
+		return node, u.unquoted, u.err
+	}
+	if err != nil {
+		return node, u.unquoted, err
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if isSplicingCall(n) && u.err == nil {
+			u.err = fmt.Errorf("unquote_splicing can only be used in argument lists, arrays and blocks, got %s", n)
+		}
+		return u.err == nil
+	})
+	return node, u.unquoted, u.err
+}
+
+type unquoter struct {
+	env      *object.Environment
+	unquoted []ast.Node
+	err      error
+}
+
+func (u *unquoter) unquote(call *ast.CallExpression) ast.Node {
+	if len(call.Arguments) != 1 {
+		return call
+	}
+	value := Eval(call.Arguments[0], u.env)
+	if isError(value) {
+		u.err = fmt.Errorf("%s", value.(*object.Error).Message)
+		return call
+	}
+	n := nodeFrom(value)
+	if n == nil {
+		u.err = fmt.Errorf("can not unquote %s", describe(value))
+		return call
+	}
+	u.unquoted = append(u.unquoted, n)
+	return n
+}
+
+// splice returns the nodes of the elements of the array an unquote_splicing
+// call evaluates to.
+func (u *unquoter) splice(call *ast.CallExpression) []ast.Node {
+	if len(call.Arguments) != 1 {
+		u.err = fmt.Errorf("wrong number of arguments to unquote_splicing. got=%d, want=1", len(call.Arguments))
+		return nil
+	}
+	value := Eval(call.Arguments[0], u.env)
+	if isError(value) {
+		u.err = fmt.Errorf("%s", value.(*object.Error).Message)
+		return nil
+	}
+	array, ok := value.(*object.Array)
+	if !ok {
+		u.err = fmt.Errorf("argument to `unquote_splicing` must be ARRAY, got %s", describe(value))
+		return nil
+	}
+	nodes := []ast.Node{}
+	for _, element := range array.Elements {
+		n := nodeFrom(element)
+		if n == nil {
+			u.err = fmt.Errorf("can not unquote %s", describe(element))
+			return nil
+		}
+		nodes = append(nodes, n)
+	}
+	u.unquoted = append(u.unquoted, nodes...)
+	return nodes
+}
+
+func (u *unquoter) spliceExpressions(expressions []ast.Expression) []ast.Expression {
+	if !containsSplicingCall(expressions) {
+		return expressions
+	}
+	spliced := []ast.Expression{}
+	for _, e := range expressions {
+		if !isSplicingCall(e) {
+			spliced = append(spliced, e)
+			continue
+		}
+		for _, n := range u.splice(e.(*ast.CallExpression)) {
+			expression, ok := n.(ast.Expression)
+			if !ok {
+				u.err = fmt.Errorf("can not splice %s into an expression list", n)
+				return expressions
+			}
+			spliced = append(spliced, expression)
+		}
+	}
+	return spliced
+}
+
+func (u *unquoter) spliceStatements(statements []ast.Statement) []ast.Statement {
+	spliced := []ast.Statement{}
+	for _, s := range statements {
+		es, ok := s.(*ast.ExpressionStatement)
+		if !ok || !isSplicingCall(es.Expression) {
+			spliced = append(spliced, s)
+			continue
+		}
+		for _, n := range u.splice(es.Expression.(*ast.CallExpression)) {
+			switch n := n.(type) {
+			case ast.Statement:
+				spliced = append(spliced, n)
+			case ast.Expression:
+				spliced = append(spliced, &ast.ExpressionStatement{Token: es.Token, Expression: n})
+			}
+		}
+	}
+	return spliced
+}
+
+func containsSplicingCall(expressions []ast.Expression) bool {
+	for _, e := range expressions {
+		if isSplicingCall(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func describe(obj object.Object) string {
+	if obj == nil {
+		return "nothing"
+	}
+	return string(obj.Type())
+}
+
+// substituteGensyms replaces identifiers naming a variable that holds a
+// gensym with the gensym, so that macros can bind values to it with let and
+// fn. Identifiers in skip are left alone. Quotes made outside macro expansion
+// are not substituted.
+func substituteGensyms(quoted ast.Node, env *object.Environment, skip map[*ast.Identifier]bool) (ast.Node, error) {
 	return ast.Modify(quoted, func(node ast.Node) ast.Node {
-		if !isUnquoteCall(node) {
+		identifier, ok := node.(*ast.Identifier)
+		if !ok || skip[identifier] {
 			return node
 		}
-		call, ok := node.(*ast.CallExpression)
+		value, ok := env.Get(identifier.Value)
 		if !ok {
 			return node
 		}
-
-		if len(call.Arguments) != 1 {
+		q, ok := value.(*object.Quote)
+		if !ok {
 			return node
 		}
-
-		unquoted := Eval(call.Arguments[0], env)
-		return nodeFrom(unquoted)
+		gensym, ok := q.Node.(*ast.Identifier)
+		if !ok || !object.IsGensym(gensym) {
+			return node
+		}
+		return &ast.Identifier{Token: gensym.Token, Value: gensym.Value}
 	})
 }
 
+// identifiersIn returns the identifiers appearing anywhere in nodes.
+func identifiersIn(nodes ...ast.Node) map[*ast.Identifier]bool {
+	identifiers := map[*ast.Identifier]bool{}
+	for _, n := range nodes {
+		ast.Inspect(n, func(node ast.Node) bool {
+			if identifier, ok := node.(*ast.Identifier); ok {
+				identifiers[identifier] = true
+			}
+			return true
+		})
+	}
+	return identifiers
+}
+
 func isUnquoteCall(node ast.Node) bool {
 	callExp, ok := node.(*ast.CallExpression)
 	if !ok {
@@ -38,6 +222,14 @@ func isUnquoteCall(node ast.Node) bool {
 	return callExp.Function.TokenLiteral() == "unquote"
 }
 
+func isSplicingCall(node ast.Node) bool {
+	callExp, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	return callExp.Function.TokenLiteral() == "unquote_splicing"
+}
+
 func nodeFrom(obj object.Object) ast.Node {
 	switch obj := obj.(type) {
 	case *object.Integer:
@@ -46,6 +238,9 @@ func nodeFrom(obj object.Object) ast.Node {
 			Literal: fmt.Sprintf("%d", obj.Value),
 		}
 		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+	case *object.BigInt:
+		t := token.Token{Type: token.INT, Literal: obj.Value.String()}
+		return &ast.BigIntegerLiteral{Token: t, Value: obj.Value}
 	case *object.Boolean:
 		var t token.Token
 		if obj.Value {
@@ -54,9 +249,44 @@ func nodeFrom(obj object.Object) ast.Node {
 			t = token.Token{Type: token.FALSE, Literal: "false"}
 		}
 		return &ast.Boolean{Token: t, Value: obj.Value}
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: escaper.Replace(obj.Value)}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}
+	case *object.Array:
+		elements := []ast.Expression{}
+		for _, element := range obj.Elements {
+			e, ok := nodeFrom(element).(ast.Expression)
+			if !ok {
+				return nil
+			}
+			elements = append(elements, e)
+		}
+		t := token.Token{Type: token.LBRACKET, Literal: "["}
+		return &ast.ArrayLiteral{Token: t, Elements: elements}
+	case *object.Hash:
+		hash := &ast.HashLiteral{
+			Token: token.Token{Type: token.LBRACE, Literal: "{"},
+			Pairs: map[ast.Expression]ast.Expression{},
+		}
+		for _, pair := range obj.Pairs() {
+			key, ok := nodeFrom(pair.Key).(ast.Expression)
+			if !ok {
+				return nil
+			}
+			value, ok := nodeFrom(pair.Value).(ast.Expression)
+			if !ok {
+				return nil
+			}
+			hash.Pairs[key] = value
+			hash.Keys = append(hash.Keys, key)
+		}
+		return hash
 	case *object.Quote:
 		return obj.Node
 	default:
 		return nil
 	}
 }
+
+// escaper turns strings back into the literals the lexer reads them from.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
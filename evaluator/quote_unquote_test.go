@@ -26,6 +26,12 @@ func TestQuote(t *testing.T) {
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
+		if array, ok := evaluated.(*object.Array); ok {
+			if array.Inspect() != tt.expected {
+				t.Errorf("not equal. got=%q, want=%q", array.Inspect(), tt.expected)
+			}
+			continue
+		}
 		quote, ok := evaluated.(*object.Quote)
 		if !ok {
 			t.Fatalf("expected *object.Quote. got=%T (%+v)",
@@ -68,6 +74,12 @@ func TestQuoteUnquote(t *testing.T) {
 			`quote(unquote(4 + 4) + 8)`,
 			`(8 + 8)`,
 		},
+		// Gensyms only take the place of variables during macro expansion.
+		{
+			`let v = gensym();
+            quote(fn(v) { v })`,
+			"fn(v) {\n    v;\n}",
+		},
 		{
 			`let foobar = 8;
             quote(foobar)`,
@@ -95,10 +107,24 @@ func TestQuoteUnquote(t *testing.T) {
             quote(unquote(4 + 4) + unquote(quotedInfixExpression))`,
 			`(8 + (4 + 4))`,
 		},
+		{
+			`quote(f(unquote(1 + 1), [unquote(2 * 2)]))`,
+			`f(2, [4])`,
+		},
+		{
+			`let twice = fn(x) { quote(unquote(x) * 2) }; [twice(1), twice(2)]`,
+			`[QUOTE((1 * 2)), QUOTE((2 * 2))]`,
+		},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
+		if array, ok := evaluated.(*object.Array); ok {
+			if array.Inspect() != tt.expected {
+				t.Errorf("not equal. got=%q, want=%q", array.Inspect(), tt.expected)
+			}
+			continue
+		}
 		quote, ok := evaluated.(*object.Quote)
 		if !ok {
 			t.Fatalf("expected *object.Quote. got=%T (%+v)",
@@ -115,3 +141,64 @@ func TestQuoteUnquote(t *testing.T) {
 		}
 	}
 }
+
+func TestUnquoteSplicing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(f(unquote_splicing([1, 2]), 3))`, `f(1, 2, 3)`},
+		{`quote(f(unquote_splicing([])))`, `f()`},
+		{`quote([0, unquote_splicing(map([1, 2], fn(x) { x * 10 }))])`, `[0, 10, 20]`},
+		{`quote([unquote_splicing([quote(a + b), quote(c)])])`, `[(a + b), c]`},
+		{`quote(if (true) { unquote_splicing([quote(a), quote(b)]) })`, "if (true) {\n    a;b;\n}"},
+		{`quote(unquote("a\"b"))`, `"a\"b"`},
+		{`quote(unquote([1, "x", true, quote(y)]))`, `[1, "x", true, y]`},
+		{`quote(unquote({"a": [1], 2: false}))`, `{"a":[1], 2:false}`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Errorf("%q expected *object.Quote. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), tt.expected)
+		}
+	}
+}
+
+func TestUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(len(1)))`, "argument to `len` not supported, got INTEGER"},
+		{`quote(unquote(fn(x) { x }))`, "can not unquote FUNCTION"},
+		{`quote()`, "wrong number of arguments to quote. got=0, want=1"},
+		{`quote(unquote_splicing([1]) + 1)`, "unquote_splicing can only be used in argument lists, arrays and blocks, got unquote_splicing([1])"},
+		{`quote(f(unquote_splicing(1)))`, "argument to `unquote_splicing` must be ARRAY, got INTEGER"},
+		{`quote(f(unquote_splicing([fn() {}])))`, "can not unquote FUNCTION"},
+		{`quote(unquote(1))`, ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("%q got error: %s", tt.input, errObj.Message)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%q expected an error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
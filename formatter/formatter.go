@@ -31,6 +31,8 @@ func Format(node ast.Node, indent int) string {
 		return out.String()
 	case *ast.IntegerLiteral:
 		return indents(indent) + v.String()
+	case *ast.BigIntegerLiteral:
+		return indents(indent) + v.String()
 	case *ast.StringLiteral:
 		return indents(indent) + v.String()
 	case *ast.Boolean:
@@ -0,0 +1,207 @@
+// Package monkey embeds the Monkey programming language in Go programs.
+package monkey
+
+import (
+	"fmt"
+	"github.com/masa-suzu/monkey/ast"
+	"github.com/masa-suzu/monkey/compiler"
+	"github.com/masa-suzu/monkey/evaluator"
+	"github.com/masa-suzu/monkey/lexer"
+	"github.com/masa-suzu/monkey/object"
+	"github.com/masa-suzu/monkey/parser"
+	"github.com/masa-suzu/monkey/vm"
+	"io"
+	"strings"
+)
+
+type Backend int
+
+const (
+	BackendEvaluator Backend = iota
+	BackendVM
+)
+
+// Error reports a failure in one of the stages running a script.
+type Error struct {
+	Stage    string
+	Messages []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s error: %s", e.Stage, strings.Join(e.Messages, "; "))
+}
+
+type Option func(*Interpreter)
+
+func WithBackend(b Backend) Option {
+	return func(i *Interpreter) { i.backend = b }
+}
+
+func WithInput(in io.Reader) Option {
+	return func(i *Interpreter) { i.ctx.In = in }
+}
+
+func WithOutput(out io.Writer) Option {
+	return func(i *Interpreter) { i.ctx.Out = out }
+}
+
+// WithOverflow sets what integer arithmetic does with results that do not fit
+// in an INTEGER. Results are promoted to BIG_INTEGERs by default.
+func WithOverflow(policy object.OverflowPolicy) Option {
+	return func(i *Interpreter) { i.ctx.Overflow = policy }
+}
+
+// WithHygienicMacros renames the identifiers macro expansions bind with let
+// and fn, so that they can not capture names passed to the macro.
+func WithHygienicMacros() Option {
+	return func(i *Interpreter) { i.ctx.HygienicMacros = true }
+}
+
+// Interpreter runs Monkey source, keeping globals alive between calls to Eval.
+type Interpreter struct {
+	backend Backend
+	ctx     *object.Context
+
+	env    *object.Environment
+	macros *object.Environment
+
+	constants   []object.Object
+	globals     []object.Object
+	symbolTable *compiler.SymbolTable
+}
+
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{ctx: object.NewStdContext()}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	i.env = object.NewEnvironmentWithContext(i.ctx)
+	i.macros = object.NewEnvironmentWithContext(i.ctx)
+	i.ctx.Macros = evaluator.MacroExpander(i.macros)
+
+	i.constants = []object.Object{}
+	i.globals = make([]object.Object, vm.GlobalSize)
+	i.symbolTable = compiler.NewSymbolTable()
+	for index, v := range object.Builtins {
+		i.symbolTable.DefineBuiltin(index, v.Name)
+	}
+	return i
+}
+
+func (i *Interpreter) Backend() Backend {
+	return i.backend
+}
+
+// Eval runs src and returns the value of its last expression.
+func (i *Interpreter) Eval(src string) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &Error{Stage: "parse", Messages: p.Errors()}
+	}
+
+	expanded, err := evaluator.Expand(program, i.macros)
+	if err != nil {
+		return nil, &Error{Stage: "macro", Messages: []string{err.Error()}}
+	}
+
+	if i.backend == BackendVM {
+		return i.runVM(expanded)
+	}
+	return i.evaluate(expanded)
+}
+
+// Call calls the global function or builtin named fnName with args.
+func (i *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	fn, ok := i.lookup(fnName)
+	if !ok {
+		return nil, &Error{Stage: "runtime", Messages: []string{"identifier not found: " + fnName}}
+	}
+
+	if i.backend == BackendVM {
+		return i.callVM(fn, args)
+	}
+	return result(evaluator.Apply(i.ctx, fn, args))
+}
+
+func (i *Interpreter) SetGlobal(name string, value object.Object) {
+	if i.backend == BackendVM {
+		symbol, ok := i.symbolTable.Resolve(name)
+		if !ok || symbol.Scope != compiler.GlobalScope {
+			symbol = i.symbolTable.Define(name)
+		}
+		i.globals[symbol.Index] = value
+		return
+	}
+	i.env.Set(name, value)
+}
+
+func (i *Interpreter) GetGlobal(name string) (object.Object, bool) {
+	if i.backend == BackendVM {
+		symbol, ok := i.symbolTable.Resolve(name)
+		if !ok || symbol.Scope != compiler.GlobalScope {
+			return nil, false
+		}
+		value := i.globals[symbol.Index]
+		return value, value != nil
+	}
+	return i.env.Get(name)
+}
+
+func (i *Interpreter) lookup(name string) (object.Object, bool) {
+	if value, ok := i.GetGlobal(name); ok {
+		return value, true
+	}
+	if builtin := object.GetBuiltinName(name); builtin != nil {
+		return builtin, true
+	}
+	return nil, false
+}
+
+func (i *Interpreter) evaluate(program *ast.Program) (object.Object, error) {
+	return result(evaluator.Eval(program, i.env))
+}
+
+func (i *Interpreter) runVM(program *ast.Program) (object.Object, error) {
+	c := compiler.NewWithState(i.symbolTable, i.constants)
+	err := c.Compile(program)
+	if err != nil {
+		return nil, &Error{Stage: "compile", Messages: []string{err.Error()}}
+	}
+	byteCode := c.ByteCode()
+	i.constants = byteCode.Constants
+
+	machine := vm.NewWithGlobalScope(byteCode, i.globals)
+	machine.Context = i.ctx
+	err = machine.Run()
+	if err != nil {
+		return nil, &Error{Stage: "runtime", Messages: []string{err.Error()}}
+	}
+
+	if !compiler.ProducesValue(program) {
+		return evaluator.NULL, nil
+	}
+	return result(machine.LastPoppedStackElement())
+}
+
+// callVM runs a throwaway program which pushes fn and args as constants and calls fn.
+func (i *Interpreter) callVM(fn object.Object, args []object.Object) (object.Object, error) {
+	machine := vm.NewWithGlobalScope(&compiler.ByteCode{Constants: i.constants}, i.globals)
+	machine.Context = i.ctx
+	obj, err := machine.Call(fn, args...)
+	if err != nil {
+		return nil, &Error{Stage: "runtime", Messages: []string{err.Error()}}
+	}
+	return result(obj)
+}
+
+func result(obj object.Object) (object.Object, error) {
+	if obj == nil {
+		return evaluator.NULL, nil
+	}
+	if err, ok := obj.(*object.Error); ok {
+		return nil, &Error{Stage: "runtime", Messages: []string{err.Message}}
+	}
+	return obj, nil
+}
@@ -0,0 +1,287 @@
+package monkey
+
+import (
+	"bytes"
+	"github.com/masa-suzu/monkey/object"
+	"testing"
+)
+
+var backends = []Backend{BackendEvaluator, BackendVM}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2", "3"},
+		{`"mon" + "key"`, "monkey"},
+		{"let x = 1;", "null"},
+		{"let f = fn(x) { x * 2 }; f(21)", "42"},
+		{"return 1;", "1"},
+		{"", "null"},
+		{"[1, 2][1]", "2"},
+		{"map([1, 2], fn(x) { x * 10 })", "[10, 20]"},
+	}
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			got, err := New(WithBackend(backend)).Eval(tt.input)
+			if err != nil {
+				t.Errorf("backend %d: %q got error: %s", backend, tt.input, err)
+				continue
+			}
+			if got.Inspect() != tt.expected {
+				t.Errorf("backend %d: %q expected=%q, got=%q", backend, tt.input, tt.expected, got.Inspect())
+			}
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		stage string
+	}{
+		{"let = 1", "parse"},
+		{`len(1)`, "runtime"},
+		{`len(1); 5`, "runtime"},
+		{`let x = len(1); x`, "runtime"},
+		{"let m = macro() { 1 }; m()", "macro"},
+	}
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			_, err := New(WithBackend(backend)).Eval(tt.input)
+			e, ok := err.(*Error)
+			if !ok {
+				t.Errorf("backend %d: %q error is not *Error. got=%T (%+v)", backend, tt.input, err, err)
+				continue
+			}
+			if e.Stage != tt.stage {
+				t.Errorf("backend %d: %q expected stage=%q, got=%q", backend, tt.input, tt.stage, e.Stage)
+			}
+		}
+	}
+
+	_, err := New(WithBackend(BackendVM)).Eval("x")
+	if e, ok := err.(*Error); !ok || e.Stage != "compile" {
+		t.Errorf("expected compile error, got=%v", err)
+	}
+}
+
+func TestSessionState(t *testing.T) {
+	for _, backend := range backends {
+		i := New(WithBackend(backend))
+		lines := []string{
+			"let one = 1;",
+			"let add = fn(x, y) { x + y };",
+			"let three = add(one, 2);",
+			`let greet = fn(name) { "Hello, " + name };`,
+		}
+		for _, line := range lines {
+			if _, err := i.Eval(line); err != nil {
+				t.Fatalf("backend %d: %q got error: %s", backend, line, err)
+			}
+		}
+
+		got, err := i.Eval("add(three, 10) + one")
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "14" {
+			t.Errorf("backend %d: expected=14, got=%s", backend, got.Inspect())
+		}
+
+		got, err = i.Eval(`greet("Monkey")`)
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "Hello, Monkey" {
+			t.Errorf("backend %d: expected=%q, got=%q", backend, "Hello, Monkey", got.Inspect())
+		}
+	}
+}
+
+func TestMacros(t *testing.T) {
+	for _, backend := range backends {
+		i := New(WithBackend(backend))
+		lines := []string{
+			`let unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) };`,
+			"let x = 10;",
+		}
+		for _, line := range lines {
+			if _, err := i.Eval(line); err != nil {
+				t.Fatalf("backend %d: %q got error: %s", backend, line, err)
+			}
+		}
+
+		got, err := i.Eval("unless(x > 5, 1, 2)")
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "2" {
+			t.Errorf("backend %d: expected=2, got=%s", backend, got.Inspect())
+		}
+	}
+}
+
+func TestMacroExpand(t *testing.T) {
+	for _, backend := range backends {
+		i := New(WithBackend(backend))
+		if _, err := i.Eval("let twice = macro(x) { quote(unquote(x) * 2) };"); err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+
+		got, err := i.Eval("macroexpand(quote(twice(twice(a))))")
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "QUOTE(((a * 2) * 2))" {
+			t.Errorf("backend %d: expected=%s, got=%s", backend, "QUOTE(((a * 2) * 2))", got.Inspect())
+		}
+	}
+
+	_, err := New(WithBackend(BackendVM)).Eval("quote(unquote(1))")
+	if e, ok := err.(*Error); !ok || e.Stage != "compile" {
+		t.Errorf("expected compile error, got=%v", err)
+	}
+}
+
+func TestHygienicMacros(t *testing.T) {
+	swap := `let swap = macro(a, b) { quote(fn() { let tmp = unquote(a); [unquote(b), tmp] }()) };`
+	for _, backend := range backends {
+		i := New(WithBackend(backend), WithHygienicMacros())
+		got, err := i.Eval(swap + "let y = 1; let tmp = 2; swap(y, tmp)")
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "[2, 1]" {
+			t.Errorf("backend %d: expected=[2, 1], got=%s", backend, got.Inspect())
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	for _, backend := range backends {
+		i := New(WithBackend(backend))
+		_, err := i.Eval("let base = 100; let add = fn(x, y) { base + x + y };")
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+
+		got, err := i.Call("add", &object.Integer{Value: 1}, &object.Integer{Value: 2})
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "103" {
+			t.Errorf("backend %d: expected=103, got=%s", backend, got.Inspect())
+		}
+
+		got, err = i.Call("len", &object.String{Value: "monkey"})
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "6" {
+			t.Errorf("backend %d: expected=6, got=%s", backend, got.Inspect())
+		}
+
+		if _, err := i.Call("missing"); err == nil {
+			t.Errorf("backend %d: expected error calling an undefined function", backend)
+		}
+		if _, err := i.Call("add", &object.Integer{Value: 1}); err == nil {
+			t.Errorf("backend %d: expected error calling with wrong number of arguments", backend)
+		}
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	for _, backend := range backends {
+		i := New(WithBackend(backend))
+		i.SetGlobal("name", &object.String{Value: "Monkey"})
+
+		got, err := i.Eval(`let greeting = "Hello, " + name;`)
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+
+		got, ok := i.GetGlobal("greeting")
+		if !ok {
+			t.Fatalf("backend %d: greeting is not defined", backend)
+		}
+		if got.Inspect() != "Hello, Monkey" {
+			t.Errorf("backend %d: expected=%q, got=%q", backend, "Hello, Monkey", got.Inspect())
+		}
+
+		i.SetGlobal("name", &object.String{Value: "Gopher"})
+		got, err = i.Eval(`name`)
+		if err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if got.Inspect() != "Gopher" {
+			t.Errorf("backend %d: expected=%q, got=%q", backend, "Gopher", got.Inspect())
+		}
+
+		if _, ok := i.GetGlobal("undefined"); ok {
+			t.Errorf("backend %d: undefined should not be defined", backend)
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	for _, backend := range backends {
+		out := &bytes.Buffer{}
+		i := New(WithBackend(backend), WithOutput(out))
+		if _, err := i.Eval(`puts("Hello, Monkey!")`); err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if out.String() != "Hello, Monkey!\n" {
+			t.Errorf("backend %d: expected=%q, got=%q", backend, "Hello, Monkey!\n", out.String())
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		policy   object.OverflowPolicy
+		input    string
+		expected string
+	}{
+		{object.OverflowWrap, "9223372036854775807 + 1", "-9223372036854775808"},
+		{object.OverflowWrap, "-9223372036854775807 - 2", "9223372036854775807"},
+		{object.OverflowWrap, "4611686018427387904 * 2", "-9223372036854775808"},
+		{object.OverflowWrap, "pow(2, 64)", "0"},
+		{object.OverflowWrap, "pow(3, 41)", "-420491770248316829"},
+		{object.OverflowError, "9223372036854775807 + 1", "runtime error: integer overflow: 9223372036854775807 + 1"},
+		{object.OverflowError, "-(-9223372036854775807 - 1)", "runtime error: integer overflow: --9223372036854775808"},
+		{object.OverflowError, "(-9223372036854775807 - 1) / -1", "runtime error: integer overflow: -9223372036854775808 / -1"},
+		{object.OverflowError, "abs(-9223372036854775807 - 1)", "runtime error: integer overflow: abs(-9223372036854775808)"},
+		{object.OverflowError, "pow(2, 64)", "runtime error: integer overflow: pow(2, 64)"},
+		{object.OverflowError, "pow(-2, 63)", "-9223372036854775808"},
+		{object.OverflowError, "9223372036854775806 + 1", "9223372036854775807"},
+		{object.OverflowPromote, "9223372036854775807 + 1", "9223372036854775808"},
+		{object.OverflowPromote, "9223372036854775807 * 9223372036854775807", "85070591730234615847396907784232501249"},
+		{object.OverflowPromote, "(9223372036854775807 + 1) - 1", "9223372036854775807"},
+		{object.OverflowPromote, "(9223372036854775807 + 1) > 9223372036854775807", "true"},
+		{object.OverflowPromote, "9223372036854775807 < 9223372036854775807 + 1", "true"},
+		{object.OverflowPromote, "-(9223372036854775807 + 2)", "-9223372036854775809"},
+		{object.OverflowPromote, "pow(2, 100)", "1267650600228229401496703205376"},
+		{object.OverflowPromote, "sqrt(pow(2, 100))", "1125899906842624"},
+		{object.OverflowPromote, "abs(-pow(10, 20))", "100000000000000000000"},
+	}
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			i := New(WithBackend(backend), WithOverflow(tt.policy))
+			got, err := i.Eval(tt.input)
+			if err != nil {
+				if err.Error() != tt.expected {
+					t.Errorf("backend %d, %s: %q expected=%q, got error %q", backend, tt.policy, tt.input, tt.expected, err)
+				}
+				continue
+			}
+			if got.Inspect() != tt.expected {
+				t.Errorf("backend %d, %s: %q expected=%q, got=%q", backend, tt.policy, tt.input, tt.expected, got.Inspect())
+			}
+		}
+	}
+}
@@ -109,7 +109,9 @@ func (l *Lexer) readString() token.Token {
 	t := token.ILLEGAL
 	for {
 		l.readChar()
-		if l.ch == '"' {
+		if l.ch == '\\' && l.peekChar() != 0 {
+			l.readChar()
+		} else if l.ch == '"' {
 			t = token.STRING
 			break
 		} else if l.ch == 0 {
@@ -130,7 +132,8 @@ func (l *Lexer) peekChar() byte {
 
 func (l *Lexer) readIdentifier() string {
 	basePosition := l.currentPosition
-	for isLetter(l.ch) {
+	// Identifiers start with a letter, which may be followed by digits.
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[basePosition:l.currentPosition]
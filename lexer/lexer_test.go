@@ -28,6 +28,7 @@ if (5 < 10 ){
 [1,2]
 {"foo":"bar"}
 macro(x, y) { x + y; };
+x1 1x;
 `
 	expected := []struct {
 		expectedType    token.TokenType
@@ -131,6 +132,10 @@ macro(x, y) { x + y; };
 		{token.SEMICOLON, ";"},
 		{token.RBRACE, "}"},
 		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x1"},
+		{token.INT, "1"},
+		{token.IDENTIFIER, "x"},
+		{token.SEMICOLON, ";"},
 
 		{token.EOF, ""}}
 	NextToken(input, expected, t)
@@ -147,6 +152,19 @@ func TestDoubleQuoteError(t *testing.T) {
 	NextToken(input, expected, t)
 }
 
+func TestEscapedString(t *testing.T) {
+	input := `"say \"hi\"\n" "back\\" "\`
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, `say \"hi\"\n`},
+		{token.STRING, `back\\`},
+		{token.ILLEGAL, `\`},
+	}
+	NextToken(input, expected, t)
+}
+
 func NextToken(input string,
 	expected []struct {
 		expectedType    token.TokenType
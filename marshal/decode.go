@@ -0,0 +1,208 @@
+package marshal
+
+import (
+	"fmt"
+	"github.com/masa-suzu/monkey/object"
+	"math/big"
+	"reflect"
+)
+
+type decoder struct {
+	visiting map[object.Object]bool
+}
+
+func newDecoder() *decoder {
+	return &decoder{visiting: map[object.Object]bool{}}
+}
+
+func (d *decoder) decode(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if obj == nil {
+		obj = object.NULL
+	}
+	if t == objectType || (t.Kind() != reflect.Interface && reflect.TypeOf(obj).AssignableTo(t)) {
+		value := reflect.New(t).Elem()
+		value.Set(reflect.ValueOf(obj))
+		return value, nil
+	}
+
+	mismatch := func(want string) (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("must be %s, got %s", want, obj.Type())
+	}
+
+	if _, ok := obj.(*object.Null); ok {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if d.visiting[obj] {
+			return reflect.Value{}, fmt.Errorf("contains a cycle through %s", obj.Type())
+		}
+		d.visiting[obj] = true
+		defer delete(d.visiting, obj)
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer, ok := obj.(*object.Integer)
+		if !ok {
+			return mismatch(object.INTEGER_OBJ)
+		}
+		value := reflect.New(t).Elem()
+		if value.OverflowInt(integer.Value) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", integer.Value, t)
+		}
+		value.SetInt(integer.Value)
+		return value, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		integer, ok := obj.(*object.Integer)
+		if !ok {
+			return mismatch(object.INTEGER_OBJ)
+		}
+		value := reflect.New(t).Elem()
+		if integer.Value < 0 || value.OverflowUint(uint64(integer.Value)) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", integer.Value, t)
+		}
+		value.SetUint(uint64(integer.Value))
+		return value, nil
+	case reflect.String:
+		str, ok := obj.(*object.String)
+		if !ok {
+			return mismatch(object.STRING_OBJ)
+		}
+		return reflect.ValueOf(str.Value).Convert(t), nil
+	case reflect.Bool:
+		boolean, ok := obj.(*object.Boolean)
+		if !ok {
+			return mismatch(object.BOOLEAN_OBJ)
+		}
+		return reflect.ValueOf(boolean.Value).Convert(t), nil
+	case reflect.Slice:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			return mismatch(object.ARRAY_OBJ)
+		}
+		value := reflect.MakeSlice(t, len(array.Elements), len(array.Elements))
+		return value, d.decodeElements(array, value)
+	case reflect.Array:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			return mismatch(object.ARRAY_OBJ)
+		}
+		if len(array.Elements) != t.Len() {
+			return reflect.Value{}, fmt.Errorf("must have %d elements, got %d", t.Len(), len(array.Elements))
+		}
+		value := reflect.New(t).Elem()
+		return value, d.decodeElements(array, value)
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return mismatch(object.HASH_OBJ)
+		}
+		value := reflect.MakeMapWithSize(t, hash.Len())
+		for _, pair := range hash.Pairs() {
+			k, err := d.decode(pair.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if !comparable(k) {
+				return reflect.Value{}, fmt.Errorf("unusable as map key: %s", pair.Key.Type())
+			}
+			v, err := d.decode(pair.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value.SetMapIndex(k, v)
+		}
+		return value, nil
+	case reflect.Struct:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return mismatch(object.HASH_OBJ)
+		}
+		value := reflect.New(t).Elem()
+		for _, f := range fields(t) {
+			fieldValue, ok := hash.Get(&object.String{Value: f.name})
+			if !ok {
+				continue
+			}
+			v, err := d.decode(fieldValue, t.Field(f.index).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s %s", f.name, err)
+			}
+			value.Field(f.index).Set(v)
+		}
+		return value, nil
+	case reflect.Ptr:
+		elem, err := d.decode(obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value := reflect.New(t.Elem())
+		value.Elem().Set(elem)
+		return value, nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return d.decodeInterface(obj, t)
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("has unsupported type %s", t)
+}
+
+func (d *decoder) decodeElements(array *object.Array, value reflect.Value) error {
+	for i, e := range array.Elements {
+		elem, err := d.decode(e, value.Type().Elem())
+		if err != nil {
+			return err
+		}
+		value.Index(i).Set(elem)
+	}
+	return nil
+}
+
+// comparable reports whether v can be used as a Go map key.
+func comparable(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return !v.IsValid() || v.Type().Comparable()
+}
+
+// decodeInterface converts obj to its natural Go counterpart stored in an interface{}.
+// Big integers become int64 if they fit in one and *big.Int otherwise.
+func (d *decoder) decodeInterface(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	var natural reflect.Type
+	switch obj := obj.(type) {
+	case *object.BigInt:
+		var value interface{} = new(big.Int).Set(obj.Value)
+		if obj.Value.IsInt64() {
+			value = obj.Value.Int64()
+		}
+		ret := reflect.New(t).Elem()
+		ret.Set(reflect.ValueOf(value))
+		return ret, nil
+	case *object.Integer:
+		natural = reflect.TypeOf(int64(0))
+	case *object.String:
+		natural = reflect.TypeOf("")
+	case *object.Boolean:
+		natural = reflect.TypeOf(false)
+	case *object.Array:
+		natural = reflect.TypeOf([]interface{}{})
+	case *object.Hash:
+		natural = reflect.TypeOf(map[interface{}]interface{}{})
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to a Go value", obj.Type())
+	}
+
+	value, err := d.decode(obj, natural)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	ret := reflect.New(t).Elem()
+	ret.Set(value)
+	return ret, nil
+}
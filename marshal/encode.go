@@ -0,0 +1,130 @@
+package marshal
+
+import (
+	"fmt"
+	"github.com/masa-suzu/monkey/object"
+	"reflect"
+	"sort"
+)
+
+type reference struct {
+	t   reflect.Type
+	ptr uintptr
+	len int
+}
+
+type encoder struct {
+	visiting map[reference]bool
+}
+
+func newEncoder() *encoder {
+	return &encoder{visiting: map[reference]bool{}}
+}
+
+func (e *encoder) encode(v reflect.Value) (object.Object, error) {
+	if !v.IsValid() {
+		return object.NULL, nil
+	}
+	if obj, ok := v.Interface().(object.Object); ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() || v.Kind() == reflect.Interface && v.IsNil() {
+			return object.NULL, nil
+		}
+		return obj, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		ref := reference{t: v.Type(), ptr: v.Pointer()}
+		if v.Kind() == reflect.Slice {
+			ref.len = v.Len()
+		}
+		if e.visiting[ref] {
+			return nil, fmt.Errorf("cycle detected through %s", v.Type())
+		}
+		e.visiting[ref] = true
+		defer delete(e.visiting, ref)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if int64(v.Uint()) < 0 {
+			return nil, fmt.Errorf("%d overflows INTEGER", v.Uint())
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Bool:
+		return object.NativeBool(v.Bool()), nil
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			elem, err := e.encode(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elem
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		hash := object.NewHash()
+		for _, k := range sortedKeys(v) {
+			if err := e.addPair(hash, k, v.MapIndex(k)); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := object.NewHash()
+		for _, f := range fields(v.Type()) {
+			value := v.Field(f.index)
+			if f.omitEmpty && value.IsZero() {
+				continue
+			}
+			if err := e.addPair(hash, reflect.ValueOf(f.name), value); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+	case reflect.Ptr, reflect.Interface:
+		return e.encode(v.Elem())
+	}
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func (e *encoder) addPair(hash *object.Hash, k, v reflect.Value) error {
+	key, err := e.encode(k)
+	if err != nil {
+		return err
+	}
+	hashKey, ok := object.AsHashable(key)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", key.Type())
+	}
+	value, err := e.encode(v)
+	if err != nil {
+		return err
+	}
+	hash.Set(hashKey, value)
+	return nil
+}
+
+// sortedKeys returns the keys of the map v in a stable order, so converted hashes are deterministic.
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keyString(keys[i]) < keyString(keys[j])
+	})
+	return keys
+}
+
+func keyString(k reflect.Value) string {
+	for k.Kind() == reflect.Interface && !k.IsNil() {
+		k = k.Elem()
+	}
+	return fmt.Sprintf("%T:%v", k.Interface(), k.Interface())
+}
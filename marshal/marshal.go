@@ -0,0 +1,127 @@
+// Package marshal converts values between Go and Monkey.
+//
+// Monkey integers, strings, booleans and null map to int64, string, bool and nil.
+// Arrays map to slices and hashes to maps, whose keys keep their Monkey types.
+// Structs are converted to and from hashes keyed by field name, which a
+// `monkey:"name"` tag overrides; `monkey:"-"` skips the field and
+// `monkey:"name,omitempty"` skips it when it holds a zero value.
+package marshal
+
+import (
+	"fmt"
+	"github.com/masa-suzu/monkey/object"
+	"reflect"
+	"strings"
+)
+
+var (
+	objectType    = reflect.TypeOf((*object.Object)(nil)).Elem()
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// ToGo converts obj to int64, *big.Int, string, bool, nil, []interface{} or map[interface{}]interface{}.
+func ToGo(obj object.Object) (interface{}, error) {
+	v, err := newDecoder().decode(obj, interfaceType)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// Unmarshal stores obj in the value pointed to by ptr.
+func Unmarshal(obj object.Object, ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("want a non-nil pointer, got %T", ptr)
+	}
+	value, err := Convert(obj, v.Elem().Type())
+	if err != nil {
+		return err
+	}
+	v.Elem().Set(value)
+	return nil
+}
+
+// Convert converts obj to a Go value of type t.
+func Convert(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	return newDecoder().decode(obj, t)
+}
+
+// FromGo converts a Go value to an object. It reports an error for values
+// which refer to themselves.
+func FromGo(v interface{}) (object.Object, error) {
+	return newEncoder().encode(reflect.ValueOf(v))
+}
+
+// CanConvert reports whether values of type t can be converted to and from objects.
+func CanConvert(t reflect.Type) bool {
+	return canConvert(t, map[reflect.Type]bool{})
+}
+
+func canConvert(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if t == objectType || t.Implements(objectType) {
+		return true
+	}
+	if visiting[t] {
+		return true
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String, reflect.Bool:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return canConvert(t.Elem(), visiting)
+	case reflect.Map:
+		return canConvert(t.Key(), visiting) && canConvert(t.Elem(), visiting)
+	case reflect.Struct:
+		for _, f := range fields(t) {
+			if !canConvert(t.Field(f.index).Type, visiting) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// fields lists the exported fields of the struct type t under their Monkey names.
+func fields(t reflect.Type) []field {
+	ret := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		omitEmpty := false
+		if tag, ok := f.Tag.Lookup("monkey"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, option := range parts[1:] {
+				if option == "omitempty" {
+					omitEmpty = true
+				}
+			}
+		}
+		ret = append(ret, field{name: name, index: i, omitEmpty: omitEmpty})
+	}
+	return ret
+}
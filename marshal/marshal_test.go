@@ -0,0 +1,266 @@
+package marshal
+
+import (
+	"github.com/masa-suzu/monkey/object"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+type point struct {
+	X int `monkey:"x"`
+	Y int `monkey:"y"`
+}
+
+type user struct {
+	Name     string            `monkey:"name"`
+	Age      int64             `monkey:"age,omitempty"`
+	Tags     []string          `monkey:"tags"`
+	Location *point            `monkey:"location"`
+	Extra    map[string]string `monkey:"extra,omitempty"`
+	Password string            `monkey:"-"`
+	Admin    bool
+	note     string
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected object.Object
+	}{
+		{nil, object.NULL},
+		{1, integer(1)},
+		{uint8(255), integer(255)},
+		{"monkey", str("monkey")},
+		{true, object.TRUE},
+		{[]int{1, 2}, array(integer(1), integer(2))},
+		{[2]string{"a", "b"}, array(str("a"), str("b"))},
+		{[]interface{}{1, "a", nil}, array(integer(1), str("a"), object.NULL)},
+		{map[string]int{"one": 1}, hash(str("one"), integer(1))},
+		{map[int64]bool{2: false}, hash(integer(2), object.FALSE)},
+		{map[interface{}]interface{}{true: "t", 1: "one"}, hash(object.TRUE, str("t"), integer(1), str("one"))},
+		{&point{X: 1, Y: 2}, hash(str("x"), integer(1), str("y"), integer(2))},
+		{
+			user{Name: "masa", Tags: []string{"go"}, Password: "secret", note: "n"},
+			hash(
+				str("name"), str("masa"),
+				str("tags"), array(str("go")),
+				str("location"), object.NULL,
+				str("Admin"), object.FALSE,
+			),
+		},
+		{(*point)(nil), object.NULL},
+		{[]int(nil), object.NULL},
+		{integer(5), integer(5)},
+	}
+
+	for _, tt := range tests {
+		got, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) got error: %s", tt.input, err)
+			continue
+		}
+		if !equal(got, tt.expected) {
+			t.Errorf("FromGo(%#v) expected=%s, got=%s", tt.input, tt.expected.Inspect(), got.Inspect())
+		}
+	}
+}
+
+func TestFromGoErrors(t *testing.T) {
+	type node struct {
+		Next *node
+	}
+	cyclic := &node{}
+	cyclic.Next = cyclic
+
+	self := []interface{}{nil}
+	self[0] = self
+
+	m := map[string]interface{}{}
+	m["m"] = m
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{cyclic, "cycle detected through *marshal.node"},
+		{self, "cycle detected through []interface {}"},
+		{m, "cycle detected through map[string]interface {}"},
+		{1.5, "unsupported type float64"},
+		{map[string]float64{"pi": 3.14}, "unsupported type float64"},
+		{uint64(1 << 63), "9223372036854775808 overflows INTEGER"},
+		{map[interface{}]int{[1]interface{}{nil}: 1}, "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		_, err := FromGo(tt.input)
+		if err == nil {
+			t.Errorf("FromGo(%T) expected error %q", tt.input, tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("FromGo(%T) expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	// Shared, acyclic references are fine.
+	p := &point{X: 1}
+	if _, err := FromGo([]*point{p, p}); err != nil {
+		t.Errorf("shared pointers got error: %s", err)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	tests := []struct {
+		input    object.Object
+		expected interface{}
+	}{
+		{object.NULL, nil},
+		{integer(1), int64(1)},
+		{&object.BigInt{Value: bigInt("123456789012345678901234567890")}, bigInt("123456789012345678901234567890")},
+		{&object.BigInt{Value: big.NewInt(-5)}, int64(-5)},
+		{array(&object.BigInt{Value: bigInt("-18446744073709551616")}), []interface{}{bigInt("-18446744073709551616")}},
+		{str("monkey"), "monkey"},
+		{object.FALSE, false},
+		{array(integer(1), str("a"), object.NULL), []interface{}{int64(1), "a", nil}},
+		{
+			hash(str("one"), integer(1), integer(2), array(object.TRUE)),
+			map[interface{}]interface{}{"one": int64(1), int64(2): []interface{}{true}},
+		},
+		{hash(object.TRUE, str("t")), map[interface{}]interface{}{true: "t"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ToGo(tt.input)
+		if err != nil {
+			t.Errorf("ToGo(%s) got error: %s", tt.input.Inspect(), err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ToGo(%s) expected=%#v, got=%#v", tt.input.Inspect(), tt.expected, got)
+		}
+	}
+
+	cyclic := array(integer(1))
+	cyclic.Elements = append(cyclic.Elements, cyclic)
+	if _, err := ToGo(cyclic); err == nil || err.Error() != "contains a cycle through ARRAY" {
+		t.Errorf("expected cycle error, got=%v", err)
+	}
+	if _, err := ToGo(&object.Builtin{}); err == nil || err.Error() != "cannot convert BUILTIN to a Go value" {
+		t.Errorf("expected conversion error, got=%v", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	input := hash(
+		str("name"), str("masa"),
+		str("age"), integer(30),
+		str("tags"), array(str("go"), str("monkey")),
+		str("location"), hash(str("x"), integer(1), str("y"), integer(2)),
+		str("Password"), str("ignored"),
+		str("unknown"), integer(1),
+	)
+
+	var u user
+	if err := Unmarshal(input, &u); err != nil {
+		t.Fatalf("Unmarshal got error: %s", err)
+	}
+	expected := user{
+		Name:     "masa",
+		Age:      30,
+		Tags:     []string{"go", "monkey"},
+		Location: &point{X: 1, Y: 2},
+	}
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("expected=%+v, got=%+v", expected, u)
+	}
+
+	tests := []struct {
+		input    object.Object
+		ptr      interface{}
+		expected string
+	}{
+		{str("1"), new(int), "must be INTEGER, got STRING"},
+		{integer(-1), new(uint), "-1 overflows uint"},
+		{array(integer(1)), new([2]int), "must have 2 elements, got 1"},
+		{hash(str("x"), str("1")), new(point), "field x must be INTEGER, got STRING"},
+		{integer(1), point{}, "want a non-nil pointer, got marshal.point"},
+	}
+
+	for _, tt := range tests {
+		err := Unmarshal(tt.input, tt.ptr)
+		if err == nil {
+			t.Errorf("Unmarshal(%s) expected error %q", tt.input.Inspect(), tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("Unmarshal(%s) expected=%q, got=%q", tt.input.Inspect(), tt.expected, err.Error())
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := user{Name: "masa", Tags: []string{}, Location: &point{X: 3}, Extra: map[string]string{"k": "v"}, Admin: true}
+
+	obj, err := FromGo(in)
+	if err != nil {
+		t.Fatalf("FromGo got error: %s", err)
+	}
+	var out user
+	if err := Unmarshal(obj, &out); err != nil {
+		t.Fatalf("Unmarshal got error: %s", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected=%+v, got=%+v", in, out)
+	}
+}
+
+func integer(v int64) *object.Integer { return &object.Integer{Value: v} }
+func str(v string) *object.String     { return &object.String{Value: v} }
+
+func bigInt(v string) *big.Int {
+	i, _ := new(big.Int).SetString(v, 10)
+	return i
+}
+
+func array(elements ...object.Object) *object.Array {
+	return &object.Array{Elements: elements}
+}
+
+func hash(kvs ...object.Object) *object.Hash {
+	h := object.NewHash()
+	for i := 0; i < len(kvs); i += 2 {
+		h.Set(kvs[i].(object.Hashable), kvs[i+1])
+	}
+	return h
+}
+
+func equal(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.Array:
+		other, ok := b.(*object.Array)
+		if !ok || len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !equal(a.Elements[i], other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		other, ok := b.(*object.Hash)
+		if !ok || a.Len() != other.Len() {
+			return false
+		}
+		for _, pair := range a.Pairs() {
+			value, ok := other.Get(pair.Key.(object.Hashable))
+			if !ok || !equal(pair.Value, value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Type() == b.Type() && a.Inspect() == b.Inspect()
+	}
+}
@@ -0,0 +1,208 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Integer arithmetic is shared by the evaluator, the VM and the builtins so
+// that all of them treat results which do not fit in an INTEGER the same way.
+
+type OverflowPolicy int
+
+const (
+	// OverflowPromote turns results into BIG_INTEGERs. It is the default.
+	OverflowPromote OverflowPolicy = iota
+	// OverflowWrap wraps results around like Go's int64 arithmetic.
+	OverflowWrap
+	// OverflowError makes arithmetic fail with an error.
+	OverflowError
+)
+
+var overflowPolicies = map[OverflowPolicy]string{
+	OverflowWrap:    "wrap",
+	OverflowError:   "error",
+	OverflowPromote: "promote",
+}
+
+func (p OverflowPolicy) String() string {
+	if name, ok := overflowPolicies[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// ParseOverflowPolicy returns the policy named "wrap", "error" or "promote".
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	for p, n := range overflowPolicies {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overflow policy %q, want wrap, error or promote", name)
+}
+
+// Arithmetic applies op, one of +, -, * and /, to two integers.
+func Arithmetic(policy OverflowPolicy, op string, left, right Object) (Object, error) {
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if lok && rok {
+		if result, ok, err := integerArithmetic(op, l.Value, r.Value); ok || err != nil {
+			return result, err
+		}
+	}
+
+	lb, err := bigValue(left)
+	if err != nil {
+		return nil, err
+	}
+	rb, err := bigValue(right)
+	if err != nil {
+		return nil, err
+	}
+	result := new(big.Int)
+	switch op {
+	case "+":
+		result.Add(lb, rb)
+	case "-":
+		result.Sub(lb, rb)
+	case "*":
+		result.Mul(lb, rb)
+	case "/":
+		if rb.Sign() == 0 {
+			return nil, fmt.Errorf("integer divide by zero")
+		}
+		result.Quo(lb, rb)
+	default:
+		return nil, fmt.Errorf("unknown integer operator: %s", op)
+	}
+	return FitInteger(policy, result, func() string {
+		return fmt.Sprintf("%s %s %s", left.Inspect(), op, right.Inspect())
+	})
+}
+
+// integerArithmetic applies op to int64 values, reporting false if the result
+// overflows.
+func integerArithmetic(op string, l, r int64) (Object, bool, error) {
+	switch op {
+	case "+":
+		result := l + r
+		if (l > 0 && r > 0 && result < 0) || (l < 0 && r < 0 && result >= 0) {
+			return nil, false, nil
+		}
+		return &Integer{Value: result}, true, nil
+	case "-":
+		result := l - r
+		if (l >= 0 && r < 0 && result < 0) || (l < 0 && r > 0 && result >= 0) {
+			return nil, false, nil
+		}
+		return &Integer{Value: result}, true, nil
+	case "*":
+		if l == 0 || r == 0 {
+			return &Integer{Value: 0}, true, nil
+		}
+		result := l * r
+		if result/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+			return nil, false, nil
+		}
+		return &Integer{Value: result}, true, nil
+	case "/":
+		if r == 0 {
+			return nil, false, fmt.Errorf("integer divide by zero")
+		}
+		if l == math.MinInt64 && r == -1 {
+			return nil, false, nil
+		}
+		return &Integer{Value: l / r}, true, nil
+	default:
+		return nil, false, fmt.Errorf("unknown integer operator: %s", op)
+	}
+}
+
+// Negate returns the negation of an integer.
+func Negate(policy OverflowPolicy, obj Object) (Object, error) {
+	if i, ok := obj.(*Integer); ok && i.Value != math.MinInt64 {
+		return &Integer{Value: -i.Value}, nil
+	}
+	value, err := bigValue(obj)
+	if err != nil {
+		return nil, err
+	}
+	return FitInteger(policy, new(big.Int).Neg(value), func() string {
+		return "-" + obj.Inspect()
+	})
+}
+
+var (
+	minInt64 = big.NewInt(math.MinInt64)
+	maxInt64 = big.NewInt(math.MaxInt64)
+	wrapMod  = new(big.Int).Lsh(big.NewInt(1), 64)
+)
+
+// FitInteger returns value as an Integer if it fits in one, and otherwise
+// applies policy. expr describes the calculation in errors.
+func FitInteger(policy OverflowPolicy, value *big.Int, expr func() string) (Object, error) {
+	if value.Cmp(minInt64) >= 0 && value.Cmp(maxInt64) <= 0 {
+		return &Integer{Value: value.Int64()}, nil
+	}
+	switch policy {
+	case OverflowError:
+		return nil, fmt.Errorf("integer overflow: %s", expr())
+	case OverflowPromote:
+		return &BigInt{Value: value}, nil
+	default:
+		wrapped := new(big.Int).Mod(value, wrapMod)
+		if wrapped.Cmp(maxInt64) > 0 {
+			wrapped.Sub(wrapped, wrapMod)
+		}
+		return &Integer{Value: wrapped.Int64()}, nil
+	}
+}
+
+func bigValue(obj Object) (*big.Int, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), nil
+	case *BigInt:
+		return obj.Value, nil
+	default:
+		return nil, fmt.Errorf("%s is not an integer", obj.Type())
+	}
+}
+
+// CompareIntegers returns -1, 0 or +1 as left is less than, equal to or
+// greater than right.
+func CompareIntegers(left, right Object) (int, error) {
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if lok && rok {
+		switch {
+		case l.Value < r.Value:
+			return -1, nil
+		case l.Value > r.Value:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	}
+	lb, err := bigValue(left)
+	if err != nil {
+		return 0, err
+	}
+	rb, err := bigValue(right)
+	if err != nil {
+		return 0, err
+	}
+	return lb.Cmp(rb), nil
+}
+
+// IsInteger reports whether obj is an INTEGER or a BIG_INTEGER.
+func IsInteger(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt:
+		return true
+	default:
+		return false
+	}
+}
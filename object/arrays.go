@@ -0,0 +1,467 @@
+package object
+
+import (
+	"sort"
+)
+
+// The array builtins never modify their arguments; they return new arrays.
+
+func arrayPush(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	array, err := arrayArg("push", args[0])
+	if err != nil {
+		return err
+	}
+	elements := make([]Object, len(array.Elements), len(array.Elements)+1)
+	copy(elements, array.Elements)
+	return &Array{Elements: append(elements, args[1])}
+}
+
+// arrayPop returns the last element of the array, which is left as it is.
+func arrayPop(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	array, err := arrayArg("pop", args[0])
+	if err != nil {
+		return err
+	}
+	length := len(array.Elements)
+	if length == 0 {
+		return nil
+	}
+	return array.Elements[length-1]
+}
+
+func arrayConcat(ctx *Context, args ...Object) Object {
+	elements := []Object{}
+	for _, arg := range args {
+		array, err := arrayArg("concat", arg)
+		if err != nil {
+			return err
+		}
+		elements = append(elements, array.Elements...)
+	}
+	return &Array{Elements: elements}
+}
+
+// arraySlice returns the elements from start up to but not including end.
+// Negative positions count from the end and positions out of range are clamped.
+func arraySlice(ctx *Context, args ...Object) Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	array, err := arrayArg("slice", args[0])
+	if err != nil {
+		return err
+	}
+	length := int64(len(array.Elements))
+	start, err := integerArg("slice", args[1])
+	if err != nil {
+		return err
+	}
+	end := length
+	if len(args) == 3 {
+		if end, err = integerArg("slice", args[2]); err != nil {
+			return err
+		}
+	}
+	start, end = clampIndex(start, length), clampIndex(end, length)
+	if start >= end {
+		return &Array{Elements: []Object{}}
+	}
+	elements := make([]Object, end-start)
+	copy(elements, array.Elements[start:end])
+	return &Array{Elements: elements}
+}
+
+func clampIndex(i, length int64) int64 {
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+func arrayReverse(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	array, err := arrayArg("reverse", args[0])
+	if err != nil {
+		return err
+	}
+	length := len(array.Elements)
+	elements := make([]Object, length)
+	for i, e := range array.Elements {
+		elements[length-1-i] = e
+	}
+	return &Array{Elements: elements}
+}
+
+// arraySort sorts integers, of any size, or strings in ascending order. A comparator
+// returning whether its first argument goes before its second can be given
+// to sort anything else.
+func arraySort(ctx *Context, args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	array, err := arrayArg("sort", args[0])
+	if err != nil {
+		return err
+	}
+	elements := make([]Object, len(array.Elements))
+	copy(elements, array.Elements)
+
+	less := func(a, b Object) (bool, *Error) {
+		if IsInteger(a) && IsInteger(b) {
+			cmp, _ := CompareIntegers(a, b)
+			return cmp < 0, nil
+		}
+		switch a := a.(type) {
+		case *String:
+			if b, ok := b.(*String); ok {
+				return a.Value < b.Value, nil
+			}
+		}
+		return false, newError("`sort` can not compare %s and %s", a.Type(), b.Type())
+	}
+	if len(args) == 2 {
+		fn, err := functionArg("sort", args[1])
+		if err != nil {
+			return err
+		}
+		less = func(a, b Object) (bool, *Error) {
+			result := ctx.Call(fn, a, b)
+			if err, ok := result.(*Error); ok {
+				return false, err
+			}
+			boolean, ok := result.(*Boolean)
+			if !ok {
+				return false, newError("comparator of `sort` must return BOOLEAN, got %s", result.Type())
+			}
+			return boolean.Value, nil
+		}
+	}
+
+	var failed *Error
+	sort.SliceStable(elements, func(i, j int) bool {
+		if failed != nil {
+			return false
+		}
+		result, err := less(elements[i], elements[j])
+		if err != nil {
+			failed = err
+		}
+		return result
+	})
+	if failed != nil {
+		return failed
+	}
+	return &Array{Elements: elements}
+}
+
+func arrayMap(ctx *Context, args ...Object) Object {
+	array, fn, err := arrayAndFunctionArgs("map", args)
+	if err != nil {
+		return err
+	}
+	elements := make([]Object, len(array.Elements))
+	for i, e := range array.Elements {
+		result := ctx.Call(fn, e)
+		if isError(result) {
+			return result
+		}
+		elements[i] = result
+	}
+	return &Array{Elements: elements}
+}
+
+func arrayFilter(ctx *Context, args ...Object) Object {
+	array, fn, err := arrayAndFunctionArgs("filter", args)
+	if err != nil {
+		return err
+	}
+	elements := []Object{}
+	for _, e := range array.Elements {
+		result := ctx.Call(fn, e)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			elements = append(elements, e)
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+// arrayReduce folds the array from the left, as reduce(array, initial, fn).
+func arrayReduce(ctx *Context, args ...Object) Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	array, err := arrayArg("reduce", args[0])
+	if err != nil {
+		return err
+	}
+	fn, err := functionArg("reduce", args[2])
+	if err != nil {
+		return err
+	}
+	result := args[1]
+	for _, e := range array.Elements {
+		result = ctx.Call(fn, result, e)
+		if isError(result) {
+			return result
+		}
+	}
+	return result
+}
+
+func arrayFind(ctx *Context, args ...Object) Object {
+	array, fn, err := arrayAndFunctionArgs("find", args)
+	if err != nil {
+		return err
+	}
+	for _, e := range array.Elements {
+		result := ctx.Call(fn, e)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return e
+		}
+	}
+	return nil
+}
+
+func arrayAny(ctx *Context, args ...Object) Object {
+	array, fn, err := arrayAndFunctionArgs("any", args)
+	if err != nil {
+		return err
+	}
+	for _, e := range array.Elements {
+		result := ctx.Call(fn, e)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return TRUE
+		}
+	}
+	return FALSE
+}
+
+func arrayAll(ctx *Context, args ...Object) Object {
+	array, fn, err := arrayAndFunctionArgs("all", args)
+	if err != nil {
+		return err
+	}
+	for _, e := range array.Elements {
+		result := ctx.Call(fn, e)
+		if isError(result) {
+			return result
+		}
+		if !isTruthy(result) {
+			return FALSE
+		}
+	}
+	return TRUE
+}
+
+// arrayZip pairs up the elements of its arguments, stopping at the shortest.
+func arrayZip(ctx *Context, args ...Object) Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+	arrays := make([]*Array, len(args))
+	length := -1
+	for i, arg := range args {
+		array, err := arrayArg("zip", arg)
+		if err != nil {
+			return err
+		}
+		arrays[i] = array
+		if length < 0 || len(array.Elements) < length {
+			length = len(array.Elements)
+		}
+	}
+	elements := make([]Object, length)
+	for i := range elements {
+		tuple := make([]Object, len(arrays))
+		for j, array := range arrays {
+			tuple[j] = array.Elements[i]
+		}
+		elements[i] = &Array{Elements: tuple}
+	}
+	return &Array{Elements: elements}
+}
+
+// arrayRange returns the integers of range(end), range(start, end) or
+// range(start, end, step), excluding end.
+func arrayRange(ctx *Context, args ...Object) Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=1, 2 or 3", len(args))
+	}
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		n, err := integerArg("range", arg)
+		if err != nil {
+			return err
+		}
+		bounds[i] = n
+	}
+	start, end, step := int64(0), bounds[0], int64(1)
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return newError("step of `range` must not be 0")
+	}
+	count := rangeLength(start, end, step)
+	if count > maxRangeLength {
+		return newError("`range` would return more than %d elements", maxRangeLength)
+	}
+	elements := make([]Object, count)
+	i := start
+	for k := range elements {
+		elements[k] = &Integer{Value: i}
+		i += step
+	}
+	return &Array{Elements: elements}
+}
+
+// maxRangeLength bounds the number of elements range returns.
+const maxRangeLength = 1 << 24
+
+// rangeLength returns the number of integers from start towards end by step,
+// excluding end. The distances are unsigned so that they can not overflow.
+func rangeLength(start, end, step int64) uint64 {
+	if step > 0 && start < end {
+		return (uint64(end)-uint64(start)-1)/uint64(step) + 1
+	}
+	if step < 0 && start > end {
+		return (uint64(start)-uint64(end)-1)/(uint64(-(step+1))+1) + 1
+	}
+	return 0
+}
+
+// arrayFlatten removes one level of nesting from an array.
+func arrayFlatten(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	array, err := arrayArg("flatten", args[0])
+	if err != nil {
+		return err
+	}
+	elements := []Object{}
+	for _, e := range array.Elements {
+		if inner, ok := e.(*Array); ok {
+			elements = append(elements, inner.Elements...)
+		} else {
+			elements = append(elements, e)
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+// arrayUnique keeps the first of the elements equal to each other.
+func arrayUnique(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	array, err := arrayArg("unique", args[0])
+	if err != nil {
+		return err
+	}
+	seen := NewHash()
+	elements := []Object{}
+	for _, e := range array.Elements {
+		if key, ok := AsHashable(e); ok {
+			if _, found := seen.Get(key); found {
+				continue
+			}
+			seen.Set(key, TRUE)
+		} else if containsEqual(elements, e) {
+			continue
+		}
+		elements = append(elements, e)
+	}
+	return &Array{Elements: elements}
+}
+
+func containsEqual(elements []Object, obj Object) bool {
+	for _, e := range elements {
+		if Equal(e, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+func arrayArg(name string, arg Object) (*Array, *Error) {
+	array, ok := arg.(*Array)
+	if !ok {
+		return nil, newError("argument to `%s` must be ARRAY, got %s", name, arg.Type())
+	}
+	return array, nil
+}
+
+func integerArg(name string, arg Object) (int64, *Error) {
+	integer, ok := arg.(*Integer)
+	if !ok {
+		return 0, newError("argument to `%s` must be INTEGER, got %s", name, arg.Type())
+	}
+	return integer.Value, nil
+}
+
+func functionArg(name string, arg Object) (Object, *Error) {
+	switch arg.(type) {
+	case *Function, *Closure, *Builtin:
+		return arg, nil
+	default:
+		return nil, newError("argument to `%s` must be a function, got %s", name, arg.Type())
+	}
+}
+
+func arrayAndFunctionArgs(name string, args []Object) (*Array, Object, *Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	array, err := arrayArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, err := functionArg(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return array, fn, nil
+}
+
+func isError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
+
+func isTruthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	default:
+		return obj != nil
+	}
+}
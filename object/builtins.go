@@ -2,9 +2,13 @@ package object
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"unicode/utf8"
 )
 
+// Builtins lists the builtin functions. The compiler refers to them by index,
+// and serialized bytecode keeps those indices: new builtins go at the end, and
+// removing or moving one needs a new bytecode version in package compiler.
 var Builtins = []struct {
 	Name    string
 	Builtin *Builtin
@@ -12,7 +16,7 @@ var Builtins = []struct {
 	{
 		Name: "len",
 		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
+			Fn: func(ctx *Context, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("wrong number of arguments. got=%d, want=1", len(args))
 				}
@@ -21,28 +25,30 @@ var Builtins = []struct {
 				case *Array:
 					return &Integer{Value: int64(len(arg.Elements))}
 				case *String:
-					return &Integer{Value: int64(len(arg.Value))}
+					return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 				default:
 					return newError("argument to `len` not supported, got %s", arg.Type())
 				}
 			},
+			Arity: 1,
 		},
 	},
 	{
 		Name: "puts",
 		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
+			Fn: func(ctx *Context, args ...Object) Object {
 				for _, arg := range args {
-					fmt.Println(arg.Inspect())
+					fmt.Fprintln(ctx.Out, arg.Inspect())
 				}
 				return nil
 			},
+			Arity: Variadic,
 		},
 	},
 	{
 		Name: "first",
 		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
+			Fn: func(ctx *Context, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("wrong number of argument. got=%d, want=1", len(args))
 				}
@@ -56,12 +62,13 @@ var Builtins = []struct {
 				}
 				return nil
 			},
+			Arity: 1,
 		},
 	},
 	{
 		Name: "last",
 		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
+			Fn: func(ctx *Context, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("wrong number of argument. got=%d, want=1", len(args))
 				}
@@ -75,12 +82,13 @@ var Builtins = []struct {
 				}
 				return nil
 			},
+			Arity: 1,
 		},
 	},
 	{
 		Name: "rest",
 		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
+			Fn: func(ctx *Context, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("wrong number of argument. got=%d, want=1", len(args))
 				}
@@ -96,26 +104,291 @@ var Builtins = []struct {
 				}
 				return nil
 			},
+			Arity: 1,
 		},
 	},
 	{
-		Name: "help",
+		Name: "gets",
 		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				fmt.Println("This is the Monkey programming language!")
-				fmt.Println("Execute exit() then exit monkey!")
-				return nil
+			Fn: func(ctx *Context, args ...Object) Object {
+				if len(args) != 0 {
+					return newError("wrong number of arguments. got=%d, want=0", len(args))
+				}
+				line, err := ctx.ReadLine()
+				if err == io.EOF && line == "" {
+					return nil
+				}
+				if err != nil && err != io.EOF {
+					return newError("could not read input: %s", err)
+				}
+				return &String{Value: line}
 			},
+			Arity: 0,
 		},
 	},
 	{
-		Name: "exit",
-		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				os.Exit(0)
-				return nil
-			},
-		},
+		Name:    "json_parse",
+		Builtin: &Builtin{Fn: jsonParse, Arity: 1},
+	},
+	{
+		Name:    "json_stringify",
+		Builtin: &Builtin{Fn: jsonStringify, Arity: Variadic},
+	},
+	{
+		Name:    "push",
+		Builtin: &Builtin{Fn: arrayPush, Arity: 2},
+	},
+	{
+		Name:    "pop",
+		Builtin: &Builtin{Fn: arrayPop, Arity: 1},
+	},
+	{
+		Name:    "concat",
+		Builtin: &Builtin{Fn: arrayConcat, Arity: Variadic},
+	},
+	{
+		Name:    "slice",
+		Builtin: &Builtin{Fn: arraySlice, Arity: Variadic},
+	},
+	{
+		Name:    "reverse",
+		Builtin: &Builtin{Fn: arrayReverse, Arity: 1},
+	},
+	{
+		Name:    "sort",
+		Builtin: &Builtin{Fn: arraySort, Arity: Variadic},
+	},
+	{
+		Name:    "map",
+		Builtin: &Builtin{Fn: arrayMap, Arity: 2},
+	},
+	{
+		Name:    "filter",
+		Builtin: &Builtin{Fn: arrayFilter, Arity: 2},
+	},
+	{
+		Name:    "reduce",
+		Builtin: &Builtin{Fn: arrayReduce, Arity: 3},
+	},
+	{
+		Name:    "find",
+		Builtin: &Builtin{Fn: arrayFind, Arity: 2},
+	},
+	{
+		Name:    "any",
+		Builtin: &Builtin{Fn: arrayAny, Arity: 2},
+	},
+	{
+		Name:    "all",
+		Builtin: &Builtin{Fn: arrayAll, Arity: 2},
+	},
+	{
+		Name:    "zip",
+		Builtin: &Builtin{Fn: arrayZip, Arity: Variadic},
+	},
+	{
+		Name:    "range",
+		Builtin: &Builtin{Fn: arrayRange, Arity: Variadic},
+	},
+	{
+		Name:    "flatten",
+		Builtin: &Builtin{Fn: arrayFlatten, Arity: 1},
+	},
+	{
+		Name:    "unique",
+		Builtin: &Builtin{Fn: arrayUnique, Arity: 1},
+	},
+	{
+		Name:    "split",
+		Builtin: &Builtin{Fn: stringSplit, Arity: 2},
+	},
+	{
+		Name:    "join",
+		Builtin: &Builtin{Fn: stringJoin, Arity: 2},
+	},
+	{
+		Name:    "trim",
+		Builtin: &Builtin{Fn: stringTrim, Arity: 1},
+	},
+	{
+		Name:    "upper",
+		Builtin: &Builtin{Fn: stringUpper, Arity: 1},
+	},
+	{
+		Name:    "lower",
+		Builtin: &Builtin{Fn: stringLower, Arity: 1},
+	},
+	{
+		Name:    "contains",
+		Builtin: &Builtin{Fn: stringContains, Arity: 2},
+	},
+	{
+		Name:    "index_of",
+		Builtin: &Builtin{Fn: stringIndexOf, Arity: 2},
+	},
+	{
+		Name:    "replace",
+		Builtin: &Builtin{Fn: stringReplace, Arity: 3},
+	},
+	{
+		Name:    "starts_with",
+		Builtin: &Builtin{Fn: stringStartsWith, Arity: 2},
+	},
+	{
+		Name:    "ends_with",
+		Builtin: &Builtin{Fn: stringEndsWith, Arity: 2},
+	},
+	{
+		Name:    "repeat",
+		Builtin: &Builtin{Fn: stringRepeat, Arity: 2},
+	},
+	{
+		Name:    "substr",
+		Builtin: &Builtin{Fn: stringSubstr, Arity: Variadic},
+	},
+	{
+		Name:    "chars",
+		Builtin: &Builtin{Fn: stringChars, Arity: 1},
+	},
+	{
+		Name:    "ord",
+		Builtin: &Builtin{Fn: stringOrd, Arity: 1},
+	},
+	{
+		Name:    "chr",
+		Builtin: &Builtin{Fn: stringChr, Arity: 1},
+	},
+	{
+		Name:    "format",
+		Builtin: &Builtin{Fn: stringFormat, Arity: Variadic},
+	},
+	{
+		Name:    "keys",
+		Builtin: &Builtin{Fn: hashKeys, Arity: 1},
+	},
+	{
+		Name:    "values",
+		Builtin: &Builtin{Fn: hashValues, Arity: 1},
+	},
+	{
+		Name:    "entries",
+		Builtin: &Builtin{Fn: hashEntries, Arity: 1},
+	},
+	{
+		Name:    "has",
+		Builtin: &Builtin{Fn: hashHas, Arity: 2},
+	},
+	{
+		Name:    "delete",
+		Builtin: &Builtin{Fn: hashDelete, Arity: 2},
+	},
+	{
+		Name:    "merge",
+		Builtin: &Builtin{Fn: hashMerge, Arity: Variadic},
+	},
+	{
+		Name:    "get",
+		Builtin: &Builtin{Fn: hashGet, Arity: Variadic},
+	},
+	{
+		Name:    "from_entries",
+		Builtin: &Builtin{Fn: hashFromEntries, Arity: 1},
+	},
+	{
+		Name:    "abs",
+		Builtin: &Builtin{Fn: mathAbs, Arity: 1},
+	},
+	{
+		Name:    "min",
+		Builtin: &Builtin{Fn: mathMin, Arity: Variadic},
+	},
+	{
+		Name:    "max",
+		Builtin: &Builtin{Fn: mathMax, Arity: Variadic},
+	},
+	{
+		Name:    "pow",
+		Builtin: &Builtin{Fn: mathPow, Arity: 2},
+	},
+	{
+		Name:    "sqrt",
+		Builtin: &Builtin{Fn: mathSqrt, Arity: 1},
+	},
+	{
+		Name:    "floor",
+		Builtin: &Builtin{Fn: mathFloor, Arity: 2},
+	},
+	{
+		Name:    "ceil",
+		Builtin: &Builtin{Fn: mathCeil, Arity: 2},
+	},
+	{
+		Name:    "clamp",
+		Builtin: &Builtin{Fn: mathClamp, Arity: 3},
+	},
+	{
+		Name:    "gcd",
+		Builtin: &Builtin{Fn: mathGcd, Arity: 2},
+	},
+	{
+		Name:    "modulo",
+		Builtin: &Builtin{Fn: mathModulo, Arity: 2},
+	},
+	{
+		Name:    "type",
+		Builtin: &Builtin{Fn: typeOf, Arity: 1},
+	},
+	{
+		Name:    "is_int",
+		Builtin: &Builtin{Fn: isType(INTEGER_OBJ), Arity: 1},
+	},
+	{
+		Name:    "is_string",
+		Builtin: &Builtin{Fn: isType(STRING_OBJ), Arity: 1},
+	},
+	{
+		Name:    "is_bool",
+		Builtin: &Builtin{Fn: isType(BOOLEAN_OBJ), Arity: 1},
+	},
+	{
+		Name:    "is_null",
+		Builtin: &Builtin{Fn: isType(NULL_OBJ), Arity: 1},
+	},
+	{
+		Name:    "is_array",
+		Builtin: &Builtin{Fn: isType(ARRAY_OBJ), Arity: 1},
+	},
+	{
+		Name:    "is_hash",
+		Builtin: &Builtin{Fn: isType(HASH_OBJ), Arity: 1},
+	},
+	{
+		Name:    "is_fn",
+		Builtin: &Builtin{Fn: isType(FUNCTION_OBJ, BUILTIN_OBJ), Arity: 1},
+	},
+	{
+		Name:    "str",
+		Builtin: &Builtin{Fn: toString, Arity: 1},
+	},
+	{
+		Name:    "int",
+		Builtin: &Builtin{Fn: toInteger, Arity: 1},
+	},
+	{
+		Name:    "arity",
+		Builtin: &Builtin{Fn: arity, Arity: 1},
+	},
+	{
+		Name:    "gensym",
+		Builtin: &Builtin{Fn: gensym, Arity: Variadic},
+	},
+	{
+		Name:    "macroexpand",
+		Builtin: &Builtin{Fn: macroExpand, Arity: 1},
+	},
+	{
+		Name:    "macroexpand_1",
+		Builtin: &Builtin{Fn: macroExpand1, Arity: 1},
 	},
 }
 
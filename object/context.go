@@ -0,0 +1,114 @@
+package object
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// Context holds the host facilities an interpreter instance hands to builtin functions.
+type Context struct {
+	In  io.Reader
+	Out io.Writer
+
+	// Overflow decides what integer arithmetic does with results that do
+	// not fit in an INTEGER.
+	Overflow OverflowPolicy
+
+	// HygienicMacros renames the identifiers macro expansions bind, so that
+	// they can not capture or shadow names at the call site.
+	HygienicMacros bool
+	// MacroTrace receives each macro call expanded, with its arguments and
+	// its expansion, unless it is nil.
+	MacroTrace io.Writer
+	// Macros expands macro calls for the macroexpand builtins.
+	Macros MacroExpander
+
+	reader *bufio.Reader
+	call   func(fn Object, args []Object) Object
+	// caller is the copy Caller returns.
+	caller *Context
+	// macroDepth counts the macro bodies being evaluated.
+	macroDepth int
+}
+
+var stdContext = NewStdContext()
+
+func NewContext(in io.Reader, out io.Writer) *Context {
+	return &Context{In: in, Out: out}
+}
+
+// NewStdContext returns a Context bound to the process's standard streams.
+func NewStdContext() *Context {
+	return NewContext(os.Stdin, os.Stdout)
+}
+
+// WithCaller returns a copy of ctx whose Call runs functions with call.
+// Backends use it to let builtins call back into the running program.
+func (ctx *Context) WithCaller(call func(fn Object, args []Object) Object) *Context {
+	// Buffer input before copying so that ctx and the copy read the same lines.
+	ctx.bufferInput()
+	copied := *ctx
+	copied.call = call
+	return &copied
+}
+
+// Caller returns a copy of ctx whose Call runs functions with call, passing
+// it ctx.
+// The copy is made the first time and reused, its settings refreshed from ctx,
+// so that calling builtins does not allocate a Context each time.
+func (ctx *Context) Caller(call func(ctx *Context, fn Object, args []Object) Object) *Context {
+	if ctx.caller == nil {
+		ctx.caller = ctx.WithCaller(func(fn Object, args []Object) Object {
+			return call(ctx, fn, args)
+		})
+	}
+	ctx.bufferInput()
+	calls := ctx.caller.call
+	*ctx.caller = *ctx
+	ctx.caller.call = calls
+	return ctx.caller
+}
+
+// Call calls fn, a function of the running program or a builtin, with args.
+func (ctx *Context) Call(fn Object, args ...Object) Object {
+	if ctx.call == nil {
+		return newError("can not call %s from a builtin here", fn.Type())
+	}
+	return ctx.call(fn, args)
+}
+
+// ExpandMacro runs expand, which evaluates the body of a macro. Quotes made
+// while it runs put gensyms in place of the variables holding them.
+func (ctx *Context) ExpandMacro(expand func()) {
+	ctx.macroDepth++
+	defer func() { ctx.macroDepth-- }()
+	expand()
+}
+
+// ExpandingMacro reports whether a macro body is being evaluated.
+func (ctx *Context) ExpandingMacro() bool {
+	return ctx.macroDepth > 0
+}
+
+// ReadLine reads a line from In without its trailing line break.
+func (ctx *Context) ReadLine() (string, error) {
+	if ctx.In == nil {
+		return "", io.EOF
+	}
+	ctx.bufferInput()
+	line, err := ctx.reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (ctx *Context) bufferInput() {
+	if ctx.reader != nil || ctx.In == nil {
+		return
+	}
+	if r, ok := ctx.In.(*bufio.Reader); ok {
+		ctx.reader = r
+	} else {
+		ctx.reader = bufio.NewReader(ctx.In)
+	}
+}
@@ -0,0 +1,99 @@
+package object
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Equal reports whether a and b hold the same value. Arrays and hashes are
+// compared element by element; functions only equal themselves.
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer, *BigInt:
+		if !IsInteger(b) {
+			return false
+		}
+		cmp, _ := CompareIntegers(a, b)
+		return cmp == 0
+	case *String:
+		other, ok := b.(*String)
+		return ok && a.Value == other.Value
+	case *Boolean:
+		other, ok := b.(*Boolean)
+		return ok && a.Value == other.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *Array:
+		other, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, e := range a.Elements {
+			if !Equal(e, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		other, ok := b.(*Hash)
+		if !ok || a.Len() != other.Len() {
+			return false
+		}
+		for _, pair := range a.Pairs() {
+			value, ok := other.Get(pair.Key.(Hashable))
+			if !ok || !Equal(pair.Value, value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// AsHashable returns obj as a Hashable if it can be used as a hash key.
+// Arrays can only be used when all of their elements can.
+func AsHashable(obj Object) (Hashable, bool) {
+	if array, ok := obj.(*Array); ok {
+		for _, e := range array.Elements {
+			if _, ok := AsHashable(e); !ok {
+				return nil, false
+			}
+		}
+		return array, true
+	}
+	switch obj := obj.(type) {
+	case *Integer, *BigInt, *String, *Boolean:
+		return obj.(Hashable), true
+	default:
+		return nil, false
+	}
+}
+
+// HashKey of a BigInt holding a value that fits in an int64 is the one of the
+// equal Integer, so that equal keys always share a bucket.
+func (b *BigInt) HashKey() HashKey {
+	if b.Value.IsInt64() {
+		return (&Integer{Value: b.Value.Int64()}).HashKey()
+	}
+	h := fnv.New64a()
+	h.Write(b.Value.Bytes())
+	value := h.Sum64()
+	if b.Value.Sign() < 0 {
+		value = ^value
+	}
+	return HashKey{Type: BIG_INTEGER_OBJ, Value: value}
+}
+
+func (ar *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, e := range ar.Elements {
+		key := e.(Hashable).HashKey()
+		h.Write([]byte(key.Type))
+		binary.BigEndian.PutUint64(buf, key.Value)
+		h.Write(buf)
+	}
+	return HashKey{Type: ar.Type(), Value: h.Sum64()}
+}
@@ -0,0 +1,102 @@
+package object
+
+import (
+	"fmt"
+	"github.com/masa-suzu/monkey/ast"
+	"github.com/masa-suzu/monkey/token"
+	"strings"
+	"sync/atomic"
+)
+
+// Generated identifiers contain gensymMark, which the lexer never puts in an
+// identifier, so they can not clash with names written in source.
+const gensymMark = "#"
+
+var gensymCount int64
+
+// Gensym returns an identifier no other identifier is named after. Its name
+// starts with prefix to keep expanded code readable.
+func Gensym(prefix string) *ast.Identifier {
+	if i := strings.Index(prefix, gensymMark); i >= 0 {
+		prefix = prefix[:i]
+	}
+	name := fmt.Sprintf("%s%s%d", prefix, gensymMark, atomic.AddInt64(&gensymCount, 1))
+	return &ast.Identifier{Token: token.Token{Type: token.IDENTIFIER, Literal: name}, Value: name}
+}
+
+// IsGensym reports whether identifier was made by Gensym.
+func IsGensym(identifier *ast.Identifier) bool {
+	return strings.Contains(identifier.Value, gensymMark)
+}
+
+// ReadableGensyms returns a copy of node in which each gensym is renamed to a
+// name the lexer reads and no other identifier of node has, so that code
+// printed from it can be parsed again.
+func ReadableGensyms(node ast.Node) ast.Node {
+	taken := map[string]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if identifier, ok := n.(*ast.Identifier); ok && !IsGensym(identifier) {
+			taken[identifier.Value] = true
+		}
+		return true
+	})
+
+	renamed := map[string]string{}
+	rename := func(gensym string) string {
+		if name, ok := renamed[gensym]; ok {
+			return name
+		}
+		prefix := gensym[:strings.Index(gensym, gensymMark)]
+		if !isIdentifier(prefix) {
+			prefix = "g"
+		}
+		for i := 1; ; i++ {
+			name := fmt.Sprintf("%s_%d", prefix, i)
+			if !taken[name] {
+				taken[name] = true
+				renamed[gensym] = name
+				return name
+			}
+		}
+	}
+
+	readable, _ := ast.Modify(ast.Copy(node), func(n ast.Node) ast.Node {
+		identifier, ok := n.(*ast.Identifier)
+		if !ok || !IsGensym(identifier) {
+			return n
+		}
+		name := rename(identifier.Value)
+		return &ast.Identifier{Token: token.Token{Type: token.IDENTIFIER, Literal: name}, Value: name}
+	})
+	return readable
+}
+
+// isIdentifier reports whether the lexer reads s as a single identifier.
+func isIdentifier(s string) bool {
+	if s == "" || token.LookupIdentifier(s) != token.IDENTIFIER {
+		return false
+	}
+	for i, ch := range s {
+		letter := 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+		if !letter && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// gensym returns a quoted fresh identifier for macros to bind values to.
+func gensym(ctx *Context, args ...Object) Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	prefix := "g"
+	if len(args) == 1 {
+		s, ok := args[0].(*String)
+		if !ok {
+			return newError("argument to `gensym` must be STRING, got %s", args[0].Type())
+		}
+		prefix = s.Value
+	}
+	return &Quote{Node: Gensym(prefix)}
+}
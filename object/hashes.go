@@ -0,0 +1,157 @@
+package object
+
+// The hash builtins never modify their arguments; they return new hashes.
+// Results list pairs in the insertion order of the hashes they come from.
+
+func hashKeys(ctx *Context, args ...Object) Object {
+	return mapPairs("keys", args, func(pair HashPair) Object {
+		return pair.Key
+	})
+}
+
+func hashValues(ctx *Context, args ...Object) Object {
+	return mapPairs("values", args, func(pair HashPair) Object {
+		return pair.Value
+	})
+}
+
+func hashEntries(ctx *Context, args ...Object) Object {
+	return mapPairs("entries", args, func(pair HashPair) Object {
+		return &Array{Elements: []Object{pair.Key, pair.Value}}
+	})
+}
+
+func hashHas(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	hash, key, err := hashAndKeyArgs("has", args)
+	if err != nil {
+		return err
+	}
+	_, ok := hash.Get(key)
+	return NativeBool(ok)
+}
+
+// hashGet returns the value of a key, or a default that is null unless given.
+func hashGet(ctx *Context, args ...Object) Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	hash, key, err := hashAndKeyArgs("get", args)
+	if err != nil {
+		return err
+	}
+	if value, ok := hash.Get(key); ok {
+		return value
+	}
+	if len(args) == 3 {
+		return args[2]
+	}
+	return nil
+}
+
+func hashDelete(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	hash, key, err := hashAndKeyArgs("delete", args)
+	if err != nil {
+		return err
+	}
+	result := hash.empty()
+	for _, pair := range hash.Pairs() {
+		if !Equal(pair.Key, key) {
+			result.Set(pair.Key.(Hashable), pair.Value)
+		}
+	}
+	return result
+}
+
+// hashMerge combines hashes, values of later hashes replacing earlier ones.
+func hashMerge(ctx *Context, args ...Object) Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+	var result *Hash
+	for _, arg := range args {
+		hash, err := hashArg("merge", arg)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			result = hash.empty()
+		}
+		for _, pair := range hash.Pairs() {
+			result.Set(pair.Key.(Hashable), pair.Value)
+		}
+	}
+	return result
+}
+
+// hashFromEntries builds a hash from an array of [key, value] arrays.
+func hashFromEntries(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	array, err := arrayArg("from_entries", args[0])
+	if err != nil {
+		return err
+	}
+	result := NewHash()
+	for _, e := range array.Elements {
+		entry, ok := e.(*Array)
+		if !ok || len(entry.Elements) != 2 {
+			return newError("entries of `from_entries` must be [key, value] arrays, got %s", e.Inspect())
+		}
+		key, ok := AsHashable(entry.Elements[0])
+		if !ok {
+			return newError("unusable as hash key: %s", entry.Elements[0].Type())
+		}
+		result.Set(key, entry.Elements[1])
+	}
+	return result
+}
+
+// empty returns an empty hash bucketing keys the way h does.
+func (h *Hash) empty() *Hash {
+	if h.hasher == nil {
+		return NewHash()
+	}
+	return NewHashWithHasher(h.hasher)
+}
+
+func mapPairs(name string, args []Object, f func(HashPair) Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	hash, err := hashArg(name, args[0])
+	if err != nil {
+		return err
+	}
+	elements := make([]Object, hash.Len())
+	for i, pair := range hash.Pairs() {
+		elements[i] = f(pair)
+	}
+	return &Array{Elements: elements}
+}
+
+func hashAndKeyArgs(name string, args []Object) (*Hash, Hashable, *Error) {
+	hash, err := hashArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := AsHashable(args[1])
+	if !ok {
+		return nil, nil, newError("unusable as hash key: %s", args[1].Type())
+	}
+	return hash, key, nil
+}
+
+func hashArg(name string, arg Object) (*Hash, *Error) {
+	hash, ok := arg.(*Hash)
+	if !ok {
+		return nil, newError("argument to `%s` must be HASH, got %s", name, arg.Type())
+	}
+	return hash, nil
+}
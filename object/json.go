@@ -0,0 +1,234 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+func jsonParse(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	str, ok := args[0].(*String)
+	if !ok {
+		return newError("argument to `json_parse` must be STRING, got %s", args[0].Type())
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(str.Value))
+	decoder.UseNumber()
+	obj, err := decodeJSON(decoder)
+	if err == nil {
+		if _, extra := decoder.Token(); extra != io.EOF {
+			err = fmt.Errorf("unexpected data after top-level value")
+		}
+	}
+	if err != nil {
+		return newError("json_parse: %s", err)
+	}
+	return obj
+}
+
+func decodeJSON(decoder *json.Decoder) (Object, error) {
+	t, err := decoder.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := t.(type) {
+	case nil:
+		return NULL, nil
+	case bool:
+		return NativeBool(t), nil
+	case string:
+		return &String{Value: t}, nil
+	case json.Number:
+		return jsonNumber(t)
+	case json.Delim:
+		switch t {
+		case '[':
+			elements := []Object{}
+			for decoder.More() {
+				e, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, e)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return &Array{Elements: elements}, nil
+		case '{':
+			hash := NewHash()
+			for decoder.More() {
+				k, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := &String{Value: k.(string)}
+				value, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				hash.Set(key, value)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return hash, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected token %v", t)
+}
+
+// maxJSONExponent bounds the exponents of the numbers json_parse decodes, as
+// 1e1000000000 would take a gigabyte of digits.
+const maxJSONExponent = 10000
+
+// jsonNumber decodes n exactly, so that integral numbers too large for an
+// INTEGER, such as 1e30, become big integers.
+func jsonNumber(n json.Number) (Object, error) {
+	if i, err := n.Int64(); err == nil {
+		return &Integer{Value: i}, nil
+	}
+	s := string(n)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exponent, err := strconv.Atoi(s[i+1:])
+		if err != nil || exponent > maxJSONExponent || exponent < -maxJSONExponent {
+			return nil, fmt.Errorf("exponent of number %s is out of range", n)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || !r.IsInt() {
+		return nil, fmt.Errorf("number %s is not an integer", n)
+	}
+	return normalize(r.Num()), nil
+}
+
+func jsonStringify(ctx *Context, args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		switch arg := args[1].(type) {
+		case *Integer:
+			if arg.Value < 0 {
+				return newError("indent to `json_stringify` must not be negative, got %d", arg.Value)
+			}
+			indent = strings.Repeat(" ", int(arg.Value))
+		case *String:
+			indent = arg.Value
+		default:
+			return newError("indent to `json_stringify` must be INTEGER or STRING, got %s", arg.Type())
+		}
+	}
+
+	var out bytes.Buffer
+	err := encodeJSON(&out, args[0], map[Object]bool{})
+	if err != nil {
+		return newError("json_stringify: %s", err)
+	}
+
+	if indent != "" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, out.Bytes(), "", indent); err != nil {
+			return newError("json_stringify: %s", err)
+		}
+		return &String{Value: indented.String()}
+	}
+	return &String{Value: out.String()}
+}
+
+func encodeJSON(out *bytes.Buffer, obj Object, visiting map[Object]bool) error {
+	switch obj := obj.(type) {
+	case *Null:
+		out.WriteString("null")
+	case *Boolean:
+		out.WriteString(strconv.FormatBool(obj.Value))
+	case *Integer:
+		out.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *BigInt:
+		out.WriteString(obj.Value.String())
+	case *String:
+		encodeJSONString(out, obj.Value)
+	case *Array:
+		if visiting[obj] {
+			return fmt.Errorf("cyclic ARRAY can not be encoded")
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		out.WriteString("[")
+		for i, e := range obj.Elements {
+			if i != 0 {
+				out.WriteString(",")
+			}
+			if err := encodeJSON(out, e, visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	case *Hash:
+		if visiting[obj] {
+			return fmt.Errorf("cyclic HASH can not be encoded")
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		// Keys such as 1 and "1" encode alike, and a JSON object with the
+		// same key twice would lose one of the values.
+		keys := make(map[string]bool, len(obj.Pairs()))
+		out.WriteString("{")
+		for i, pair := range obj.Pairs() {
+			if i != 0 {
+				out.WriteString(",")
+			}
+			key, err := jsonKey(pair.Key)
+			if err != nil {
+				return err
+			}
+			if keys[key] {
+				return fmt.Errorf("duplicate object key %q", key)
+			}
+			keys[key] = true
+			encodeJSONString(out, key)
+			out.WriteString(":")
+			if err := encodeJSON(out, pair.Value, visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	default:
+		return fmt.Errorf("%s can not be encoded", obj.Type())
+	}
+	return nil
+}
+
+// jsonKey returns the name a hash key takes as a JSON object member.
+func jsonKey(key Object) (string, error) {
+	switch key := key.(type) {
+	case *String:
+		return key.Value, nil
+	case *Integer, *BigInt, *Boolean:
+		return key.Inspect(), nil
+	default:
+		return "", fmt.Errorf("%s can not be encoded as an object key", key.Type())
+	}
+}
+
+func encodeJSONString(out *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	out.Truncate(out.Len() - 1)
+}
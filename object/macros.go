@@ -0,0 +1,38 @@
+package object
+
+import (
+	"github.com/masa-suzu/monkey/ast"
+)
+
+// A MacroExpander expands the macro calls in node, all of them or only a
+// call at the top of node, once.
+type MacroExpander func(node ast.Node, all bool) (ast.Node, error)
+
+// macroExpand returns a quote of its quoted argument with every macro call
+// expanded, as running it would.
+func macroExpand(ctx *Context, args ...Object) Object {
+	return expandQuote(ctx, "macroexpand", true, args)
+}
+
+// macroExpand1 expands its quoted argument once if it is a macro call.
+func macroExpand1(ctx *Context, args ...Object) Object {
+	return expandQuote(ctx, "macroexpand_1", false, args)
+}
+
+func expandQuote(ctx *Context, name string, all bool, args []Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	quote, ok := args[0].(*Quote)
+	if !ok {
+		return newError("argument to `%s` must be QUOTE, got %s", name, args[0].Type())
+	}
+	if ctx.Macros == nil {
+		return quote
+	}
+	expanded, err := ctx.Macros(quote.Node, all)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return &Quote{Node: expanded}
+}
@@ -0,0 +1,235 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// The math builtins take INTEGERs and BIG_INTEGERs alike and apply the
+// overflow policy of their Context to results that do not fit in an INTEGER.
+
+func mathAbs(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	x, err := bigArg("abs", args[0])
+	if err != nil {
+		return err
+	}
+	if x.Sign() >= 0 {
+		return args[0]
+	}
+	return fitInteger(ctx, new(big.Int).Neg(x), "abs(%s)", args[0].Inspect())
+}
+
+// mathMin returns the smallest of its arguments or of the elements of a
+// single array argument.
+func mathMin(ctx *Context, args ...Object) Object {
+	return extremum("min", args, -1)
+}
+
+func mathMax(ctx *Context, args ...Object) Object {
+	return extremum("max", args, 1)
+}
+
+func extremum(name string, args []Object, sign int) Object {
+	if len(args) == 1 {
+		if array, ok := args[0].(*Array); ok {
+			args = array.Elements
+		}
+	}
+	if len(args) == 0 {
+		return newError("`%s` needs at least one integer", name)
+	}
+	var result Object
+	for _, arg := range args {
+		if !IsInteger(arg) {
+			return newError("argument to `%s` must be INTEGER, got %s", name, arg.Type())
+		}
+		if result == nil {
+			result = arg
+			continue
+		}
+		if cmp, _ := CompareIntegers(arg, result); cmp == sign {
+			result = arg
+		}
+	}
+	return result
+}
+
+// mathPow raises base to a non-negative exponent.
+func mathPow(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	base, err := bigArg("pow", args[0])
+	if err != nil {
+		return err
+	}
+	exp, err := integerArg("pow", args[1])
+	if err != nil {
+		return err
+	}
+	if exp < 0 {
+		return newError("exponent of `pow` must not be negative, got %d", exp)
+	}
+	e := big.NewInt(exp)
+	switch {
+	case ctx.Overflow == OverflowWrap:
+		// Only the low 64 bits are kept, so there is no need for the rest.
+		return fitInteger(ctx, new(big.Int).Exp(base, e, wrapMod), "")
+	case ctx.Overflow == OverflowError && base.BitLen() > 1 && exp > 64/int64(base.BitLen()-1):
+		return newError("integer overflow: pow(%s, %d)", args[0].Inspect(), exp)
+	case exp > 0 && base.BitLen() > 1 && int64(base.BitLen()) > maxBigIntBits/exp:
+		return newError("`pow` would return more than %d bits", maxBigIntBits)
+	}
+	return fitInteger(ctx, new(big.Int).Exp(base, e, nil), "pow(%s, %d)", args[0].Inspect(), exp)
+}
+
+// maxBigIntBits bounds the length in bits of the results pow builds.
+const maxBigIntBits = 1 << 24
+
+// mathSqrt returns the integer square root, rounded down.
+func mathSqrt(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	x, err := bigArg("sqrt", args[0])
+	if err != nil {
+		return err
+	}
+	if x.Sign() < 0 {
+		return newError("argument to `sqrt` must not be negative, got %s", x)
+	}
+	return normalize(new(big.Int).Sqrt(x))
+}
+
+// mathFloor divides a by b, rounding towards negative infinity.
+func mathFloor(ctx *Context, args ...Object) Object {
+	return divide(ctx, "floor", args, func(q, r, b *big.Int) {
+		if r.Sign() != 0 && (r.Sign() < 0) != (b.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		}
+	})
+}
+
+// mathCeil divides a by b, rounding towards positive infinity.
+func mathCeil(ctx *Context, args ...Object) Object {
+	return divide(ctx, "ceil", args, func(q, r, b *big.Int) {
+		if r.Sign() != 0 && (r.Sign() < 0) == (b.Sign() < 0) {
+			q.Add(q, big.NewInt(1))
+		}
+	})
+}
+
+func divide(ctx *Context, name string, args []Object, round func(q, r, b *big.Int)) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, b, err := twoBigArgs(name, args)
+	if err != nil {
+		return err
+	}
+	if b.Sign() == 0 {
+		return newError("integer divide by zero")
+	}
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	round(q, r, b)
+	return fitInteger(ctx, q, "%s(%s, %s)", name, args[0].Inspect(), args[1].Inspect())
+}
+
+// mathModulo returns the remainder of floor division, which has the sign of
+// the divisor.
+func mathModulo(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, b, err := twoBigArgs("modulo", args)
+	if err != nil {
+		return err
+	}
+	if b.Sign() == 0 {
+		return newError("integer divide by zero")
+	}
+	r := new(big.Int).Rem(a, b)
+	if r.Sign() != 0 && (r.Sign() < 0) != (b.Sign() < 0) {
+		r.Add(r, b)
+	}
+	return normalize(r)
+}
+
+// mathClamp limits x to the range from lo to hi, as clamp(x, lo, hi).
+func mathClamp(ctx *Context, args ...Object) Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	for _, arg := range args {
+		if !IsInteger(arg) {
+			return newError("argument to `clamp` must be INTEGER, got %s", arg.Type())
+		}
+	}
+	x, lo, hi := args[0], args[1], args[2]
+	if cmp, _ := CompareIntegers(lo, hi); cmp > 0 {
+		return newError("bounds of `clamp` are reversed: %s > %s", lo.Inspect(), hi.Inspect())
+	}
+	if cmp, _ := CompareIntegers(x, lo); cmp < 0 {
+		return lo
+	}
+	if cmp, _ := CompareIntegers(x, hi); cmp > 0 {
+		return hi
+	}
+	return x
+}
+
+// mathGcd returns the greatest common divisor, which is never negative.
+func mathGcd(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, b, err := twoBigArgs("gcd", args)
+	if err != nil {
+		return err
+	}
+	gcd := new(big.Int).GCD(nil, nil, new(big.Int).Abs(a), new(big.Int).Abs(b))
+	return fitInteger(ctx, gcd, "gcd(%s, %s)", args[0].Inspect(), args[1].Inspect())
+}
+
+// fitInteger is FitInteger for builtins, describing overflows with format.
+func fitInteger(ctx *Context, value *big.Int, format string, a ...interface{}) Object {
+	result, err := FitInteger(ctx.Overflow, value, func() string {
+		return fmt.Sprintf(format, a...)
+	})
+	if err != nil {
+		return newError("%s", err)
+	}
+	return result
+}
+
+// normalize returns value as an Integer if it fits in one. It is used for
+// results no larger than the arguments they come from.
+func normalize(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInt{Value: value}
+}
+
+func twoBigArgs(name string, args []Object) (*big.Int, *big.Int, *Error) {
+	a, err := bigArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := bigArg(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+func bigArg(name string, arg Object) (*big.Int, *Error) {
+	value, err := bigValue(arg)
+	if err != nil {
+		return nil, newError("argument to `%s` must be INTEGER, got %s", name, arg.Type())
+	}
+	return value, nil
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/code"
 	"hash/fnv"
+	"math/big"
+	"sort"
 	"strings"
 )
 
@@ -13,6 +15,7 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ           = "INTEGER"
+	BIG_INTEGER_OBJ       = "BIG_INTEGER"
 	STRING_OBJ            = "STRING"
 	BOOLEAN_OBJ           = "BOOLEAN"
 	NULL_OBJ              = "NULL"
@@ -28,6 +31,13 @@ const (
 	CLOSURE_OBJ           = "CLOSURE"
 )
 
+// Singletons shared by every backend, so objects can be passed between them.
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
@@ -37,6 +47,12 @@ type Integer struct {
 	Value int64
 }
 
+// BigInt holds an integer that does not fit in an Integer. Arithmetic
+// results that fit are always turned back into Integers.
+type BigInt struct {
+	Value *big.Int
+}
+
 type String struct {
 	Value string
 }
@@ -61,11 +77,16 @@ type Function struct {
 	Env        *Environment
 }
 
-type BuiltinFunction func(args ...Object) Object
+type BuiltinFunction func(ctx *Context, args ...Object) Object
 type Builtin struct {
 	Fn BuiltinFunction
+	// Arity is the number of arguments Fn takes, or Variadic.
+	Arity int
 }
 
+// Variadic is the Arity of builtins taking a varying number of arguments.
+const Variadic = -1
+
 type Array struct {
 	Elements []Object
 }
@@ -73,6 +94,7 @@ type Array struct {
 type Environment struct {
 	store map[string]Object
 	outer *Environment
+	ctx   *Context
 }
 
 type Quote struct {
@@ -110,6 +132,12 @@ func NewEnvironment() *Environment {
 	return &Environment{store: s}
 }
 
+func NewEnvironmentWithContext(ctx *Context) *Environment {
+	env := NewEnvironment()
+	env.ctx = ctx
+	return env
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -151,17 +179,97 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash maps keys to values, remembering the order in which keys were first set.
+// Keys are bucketed by their HashKey and told apart with Equal, so keys whose
+// HashKeys collide still map to separate values.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	hasher Hasher
+	index  map[HashKey][]int
+	pairs  []HashPair
 }
 
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
+// Hasher computes the bucket a key of a Hash is stored in.
+type Hasher func(key Hashable) HashKey
+
+func defaultHasher(key Hashable) HashKey {
+	return key.HashKey()
+}
+
+func NewHash() *Hash {
+	return NewHashWithHasher(defaultHasher)
+}
+
+// NewHashWithHasher returns an empty Hash bucketing its keys with hasher.
+func NewHashWithHasher(hasher Hasher) *Hash {
+	return &Hash{hasher: hasher, index: make(map[HashKey][]int)}
+}
+
+// Set maps key to value. A key set again keeps its original position.
+func (h *Hash) Set(key Hashable, value Object) {
+	if h.index == nil {
+		h.index = make(map[HashKey][]int)
+	}
+	hashed := h.hash(key)
+	if i, ok := h.find(hashed, key); ok {
+		h.pairs[i] = HashPair{Key: key, Value: value}
+		return
+	}
+	h.index[hashed] = append(h.index[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	i, ok := h.find(h.hash(key), key)
+	if !ok {
+		return nil, false
+	}
+	return h.pairs[i].Value, true
+}
+
+func (h *Hash) hash(key Hashable) HashKey {
+	if h.hasher == nil {
+		return defaultHasher(key)
+	}
+	return h.hasher(key)
+}
+
+// find returns the position of key among the pairs in the bucket hashed.
+func (h *Hash) find(hashed HashKey, key Hashable) (int, bool) {
+	for _, i := range h.index[hashed] {
+		if Equal(h.pairs[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Pairs returns the pairs of h in insertion order.
+func (h *Hash) Pairs() []HashPair {
+	return h.pairs
+}
+
+func (h *Hash) Len() int {
+	return len(h.pairs)
+}
+
+func NativeBool(value bool) *Boolean {
+	if value {
+		return TRUE
+	}
+	return FALSE
+}
+
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+func (b *BigInt) Inspect() string  { return b.Value.String() }
+func (b *BigInt) Type() ObjectType { return BIG_INTEGER_OBJ }
+
 func (s *String) Inspect() string  { return s.Value }
 func (s *String) Type() ObjectType { return STRING_OBJ }
 
@@ -226,6 +334,33 @@ func (env *Environment) Set(name string, obj Object) {
 	env.store[name] = obj
 }
 
+// Names returns the sorted names defined in env and the environments around it.
+func (env *Environment) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for e := env; e != nil; e = e.outer {
+		for name := range e.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Context returns the Context of the outermost environment, defaulting to the standard streams.
+func (env *Environment) Context() *Context {
+	if env.ctx != nil {
+		return env.ctx
+	}
+	if env.outer != nil {
+		return env.outer.Context()
+	}
+	return stdContext
+}
+
 func (b *Boolean) HashKey() HashKey {
 	if b.Value {
 		return HashKey{Type: b.Type(), Value: 1}
@@ -247,7 +382,7 @@ func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 	out.WriteString("{")
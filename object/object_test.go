@@ -1,6 +1,9 @@
 package object
 
 import (
+	"bytes"
+	"math/big"
+	"reflect"
 	"testing"
 )
 
@@ -87,8 +90,192 @@ func TestHashType(t *testing.T) {
 	}
 }
 
+func TestHashOrder(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&String{Value: "b"}, &Integer{Value: 1})
+	hash.Set(&Integer{Value: 2}, &Integer{Value: 2})
+	hash.Set(TRUE, &Integer{Value: 3})
+	hash.Set(&String{Value: "b"}, &Integer{Value: 4})
+
+	if hash.Len() != 3 {
+		t.Fatalf("hash has wrong num of pairs. got=%d", hash.Len())
+	}
+	if hash.Inspect() != "{b: 4, 2: 2, true: 3}" {
+		t.Errorf("hash.Inspect() wrong. got=%q", hash.Inspect())
+	}
+	value, ok := hash.Get(&String{Value: "b"})
+	if !ok || value.Inspect() != "4" {
+		t.Errorf("hash.Get(b) wrong. got=%v, %t", value, ok)
+	}
+	if _, ok := hash.Get(FALSE); ok {
+		t.Errorf("hash.Get(false) should not be found")
+	}
+}
+
+func TestHashCollisions(t *testing.T) {
+	collide := func(key Hashable) HashKey {
+		return HashKey{Type: STRING_OBJ, Value: 42}
+	}
+	hash := NewHashWithHasher(collide)
+	keys := []Hashable{
+		&String{Value: "a"},
+		&String{Value: "b"},
+		&Integer{Value: 1},
+		TRUE,
+		&Array{Elements: []Object{&String{Value: "a"}}},
+	}
+	for i, key := range keys {
+		hash.Set(key, &Integer{Value: int64(i)})
+	}
+	hash.Set(&String{Value: "b"}, &Integer{Value: 10})
+
+	if hash.Len() != len(keys) {
+		t.Fatalf("hash has wrong num of pairs. want=%d, got=%d", len(keys), hash.Len())
+	}
+	if hash.Inspect() != "{a: 0, b: 10, 1: 2, true: 3, [a]: 4}" {
+		t.Errorf("hash.Inspect() wrong. got=%q", hash.Inspect())
+	}
+	for i, key := range keys {
+		want := int64(i)
+		if i == 1 {
+			want = 10
+		}
+		value, ok := hash.Get(key)
+		if !ok {
+			t.Errorf("hash.Get(%s) not found", key.Inspect())
+			continue
+		}
+		if value.(*Integer).Value != want {
+			t.Errorf("hash.Get(%s) wrong. want=%d, got=%s", key.Inspect(), want, value.Inspect())
+		}
+	}
+	if _, ok := hash.Get(&String{Value: "c"}); ok {
+		t.Errorf("hash.Get(c) should not be found")
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	one := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	same := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	other := &Array{Elements: []Object{&String{Value: "a"}, &Integer{Value: 1}}}
+
+	if one.HashKey() != same.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+	if one.HashKey() == other.HashKey() {
+		t.Errorf("arrays with different content have same hash keys")
+	}
+
+	if _, ok := AsHashable(one); !ok {
+		t.Errorf("array of integers and strings should be hashable")
+	}
+	nested := &Array{Elements: []Object{one, &Function{}}}
+	if _, ok := AsHashable(nested); ok {
+		t.Errorf("array containing a function should not be hashable")
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	big1 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}
+	big2 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}
+	negative := &BigInt{Value: new(big.Int).Neg(big1.Value)}
+	small := &BigInt{Value: big.NewInt(42)}
+
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same content have different hash keys")
+	}
+	if big1.HashKey() == negative.HashKey() {
+		t.Errorf("big integers with different signs have same hash keys")
+	}
+	if small.HashKey() != (&Integer{Value: 42}).HashKey() {
+		t.Errorf("big integer fitting an int64 has different hash key from equal integer")
+	}
+	if !Equal(small, &Integer{Value: 42}) || Equal(big1, negative) {
+		t.Errorf("big integers compare wrongly")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	fn := &Function{}
+	hash := func(pairs ...Object) *Hash {
+		h := NewHash()
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i].(Hashable), pairs[i+1])
+		}
+		return h
+	}
+	array := func(elements ...Object) *Array {
+		return &Array{Elements: elements}
+	}
+	one := &Integer{Value: 1}
+	a := &String{Value: "a"}
+
+	tests := []struct {
+		left, right Object
+		want        bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &String{Value: "1"}, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&Null{}, NULL, true},
+		{NULL, FALSE, false},
+		{array(one, a), array(&Integer{Value: 1}, &String{Value: "a"}), true},
+		{array(one, a), array(a, one), false},
+		{array(one), array(one, one), false},
+		{array(array(one)), array(array(one)), true},
+		{hash(a, one, one, a), hash(one, a, a, one), true},
+		{hash(a, one), hash(a, a), false},
+		{hash(a, one), hash(a, one, one, one), false},
+		{fn, fn, true},
+		{fn, &Function{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Equal(tt.left, tt.right); got != tt.want {
+			t.Errorf("Equal(%s, %s) wrong. want=%t, got=%t",
+				tt.left.Inspect(), tt.right.Inspect(), tt.want, got)
+		}
+	}
+}
+
 func testObject(t *testing.T, obj Object, expected ObjectType) {
 	if obj.Type() != expected {
 		t.Fatalf("obj.Type() is different from %T. got=%T", expected, obj.Type())
 	}
 }
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("b", TRUE)
+	outer.Set("c", TRUE)
+	env := NewEnclosedEnvironment(outer)
+	env.Set("a", TRUE)
+	env.Set("b", FALSE)
+
+	want := []string{"a", "b", "c"}
+	if got := env.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got=%q", want, got)
+	}
+}
+
+func TestContextCaller(t *testing.T) {
+	ctx := NewContext(nil, &bytes.Buffer{})
+	var called *Context
+	call := func(ctx *Context, fn Object, args []Object) Object {
+		called = ctx
+		return args[0]
+	}
+
+	caller := ctx.Caller(call)
+	if again := ctx.Caller(call); again != caller {
+		t.Errorf("Caller made a new Context")
+	}
+	if result := caller.Call(&Null{}, &Integer{Value: 1}); result.Inspect() != "1" || called != ctx {
+		t.Errorf("Call did not run call with ctx. got=%s", result.Inspect())
+	}
+
+	ctx.Out = &bytes.Buffer{}
+	if ctx.Caller(call).Out != ctx.Out {
+		t.Errorf("Caller did not refresh Out")
+	}
+}
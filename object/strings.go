@@ -0,0 +1,334 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Strings are measured, indexed and sliced by runes, not bytes.
+
+// StringIndex returns the rune at position i of s as a string, or NULL if
+// there is no such rune.
+func StringIndex(s *String, i int64) Object {
+	if i < 0 {
+		return NULL
+	}
+	for _, r := range s.Value {
+		if i == 0 {
+			return &String{Value: string(r)}
+		}
+		i--
+	}
+	return NULL
+}
+
+func stringSplit(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, err := stringArg("split", args[0])
+	if err != nil {
+		return err
+	}
+	sep, err := stringArg("split", args[1])
+	if err != nil {
+		return err
+	}
+	return stringArray(strings.Split(s, sep))
+}
+
+func stringJoin(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	array, err := arrayArg("join", args[0])
+	if err != nil {
+		return err
+	}
+	sep, err := stringArg("join", args[1])
+	if err != nil {
+		return err
+	}
+	parts := make([]string, len(array.Elements))
+	for i, e := range array.Elements {
+		s, ok := e.(*String)
+		if !ok {
+			return newError("elements of `join` must be STRING, got %s", e.Type())
+		}
+		parts[i] = s.Value
+	}
+	return &String{Value: strings.Join(parts, sep)}
+}
+
+func stringTrim(ctx *Context, args ...Object) Object {
+	return mapString("trim", args, strings.TrimSpace)
+}
+
+func stringUpper(ctx *Context, args ...Object) Object {
+	return mapString("upper", args, strings.ToUpper)
+}
+
+func stringLower(ctx *Context, args ...Object) Object {
+	return mapString("lower", args, strings.ToLower)
+}
+
+func stringContains(ctx *Context, args ...Object) Object {
+	return testStrings("contains", args, strings.Contains)
+}
+
+func stringStartsWith(ctx *Context, args ...Object) Object {
+	return testStrings("starts_with", args, strings.HasPrefix)
+}
+
+func stringEndsWith(ctx *Context, args ...Object) Object {
+	return testStrings("ends_with", args, strings.HasSuffix)
+}
+
+// stringIndexOf returns the rune position of the first occurrence of a
+// substring, or -1 if there is none.
+func stringIndexOf(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, err := stringArg("index_of", args[0])
+	if err != nil {
+		return err
+	}
+	sub, err := stringArg("index_of", args[1])
+	if err != nil {
+		return err
+	}
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return &Integer{Value: -1}
+	}
+	return &Integer{Value: int64(utf8.RuneCountInString(s[:i]))}
+}
+
+// stringReplace replaces every occurrence of old with new.
+func stringReplace(ctx *Context, args ...Object) Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	values := make([]string, len(args))
+	for i, arg := range args {
+		s, err := stringArg("replace", arg)
+		if err != nil {
+			return err
+		}
+		values[i] = s
+	}
+	return &String{Value: strings.Replace(values[0], values[1], values[2], -1)}
+}
+
+func stringRepeat(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, err := stringArg("repeat", args[0])
+	if err != nil {
+		return err
+	}
+	count, err := integerArg("repeat", args[1])
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return newError("count of `repeat` must not be negative, got %d", count)
+	}
+	if len(s) > 0 && count > int64(maxStringLength/len(s)) {
+		return newError("result of `repeat` would be longer than %d bytes", maxStringLength)
+	}
+	return &String{Value: strings.Repeat(s, int(count))}
+}
+
+// maxStringLength bounds the length in bytes of the strings repeat builds.
+const maxStringLength = 1 << 30
+
+// stringSubstr returns up to length runes starting at start, as
+// substr(s, start, length). A negative start counts from the end and the
+// rest of the string is taken when length is left out.
+func stringSubstr(ctx *Context, args ...Object) Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	s, err := stringArg("substr", args[0])
+	if err != nil {
+		return err
+	}
+	runes := []rune(s)
+	start, err := integerArg("substr", args[1])
+	if err != nil {
+		return err
+	}
+	start = clampIndex(start, int64(len(runes)))
+	end := int64(len(runes))
+	if len(args) == 3 {
+		length, err := integerArg("substr", args[2])
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			return newError("length of `substr` must not be negative, got %d", length)
+		}
+		if length < end-start {
+			end = start + length
+		}
+	}
+	return &String{Value: string(runes[start:end])}
+}
+
+func stringChars(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	s, err := stringArg("chars", args[0])
+	if err != nil {
+		return err
+	}
+	chars := []string{}
+	for _, r := range s {
+		chars = append(chars, string(r))
+	}
+	return stringArray(chars)
+}
+
+func stringOrd(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	s, err := stringArg("ord", args[0])
+	if err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return newError("argument to `ord` must be a single character, got %q", s)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return &Integer{Value: int64(r)}
+}
+
+func stringChr(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	code, err := integerArg("chr", args[0])
+	if err != nil {
+		return err
+	}
+	if code < 0 || code > utf8.MaxRune || !utf8.ValidRune(rune(code)) {
+		return newError("%d is not a valid character code", code)
+	}
+	return &String{Value: string(rune(code))}
+}
+
+// stringFormat formats its arguments like Go's fmt.Sprintf. %d, %x, %o, %b
+// and %c take integers, %t takes booleans, and %s, %q and %v take anything,
+// using its inspected form. Flags, width and precision are supported.
+func stringFormat(ctx *Context, args ...Object) Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+	format, err := stringArg("format", args[0])
+	if err != nil {
+		return err
+	}
+	args = args[1:]
+
+	var out strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			return newError("format: %q ends in an incomplete verb", format)
+		}
+		verb := format[start : i+1]
+		if format[i] == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if len(args) == 0 {
+			return newError("format: missing argument for %s", verb)
+		}
+		value, err := formatValue(verb, args[0])
+		if err != nil {
+			return err
+		}
+		args = args[1:]
+		out.WriteString(fmt.Sprintf(verb, value))
+	}
+	if len(args) > 0 {
+		return newError("format: %d arguments left over", len(args))
+	}
+	return &String{Value: out.String()}
+}
+
+// formatValue returns the Go value arg is formatted as by verb.
+func formatValue(verb string, arg Object) (interface{}, *Error) {
+	switch verb[len(verb)-1] {
+	case 'd', 'x', 'X', 'o', 'b', 'c':
+		if integer, ok := arg.(*Integer); ok {
+			return integer.Value, nil
+		}
+		return nil, newError("format: %s wants INTEGER, got %s", verb, arg.Type())
+	case 't':
+		if boolean, ok := arg.(*Boolean); ok {
+			return boolean.Value, nil
+		}
+		return nil, newError("format: %s wants BOOLEAN, got %s", verb, arg.Type())
+	case 's', 'q', 'v':
+		return arg.Inspect(), nil
+	default:
+		return nil, newError("format: unknown verb %s", verb)
+	}
+}
+
+func mapString(name string, args []Object, f func(string) string) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	s, err := stringArg(name, args[0])
+	if err != nil {
+		return err
+	}
+	return &String{Value: f(s)}
+}
+
+func testStrings(name string, args []Object, f func(s, sub string) bool) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, err := stringArg(name, args[0])
+	if err != nil {
+		return err
+	}
+	sub, err := stringArg(name, args[1])
+	if err != nil {
+		return err
+	}
+	return NativeBool(f(s, sub))
+}
+
+func stringArray(values []string) *Array {
+	elements := make([]Object, len(values))
+	for i, v := range values {
+		elements[i] = &String{Value: v}
+	}
+	return &Array{Elements: elements}
+}
+
+func stringArg(name string, arg Object) (string, *Error) {
+	s, ok := arg.(*String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, arg.Type())
+	}
+	return s.Value, nil
+}
@@ -0,0 +1,98 @@
+package object
+
+import (
+	"math/big"
+	"strings"
+)
+
+// typeNames are the names type() reports. Both backends report the same
+// names, so closures of the VM are FUNCTIONs and big integers are INTEGERs.
+var typeNames = map[ObjectType]string{
+	BIG_INTEGER_OBJ:       INTEGER_OBJ,
+	CLOSURE_OBJ:           FUNCTION_OBJ,
+	COMPILED_FUNCTION_OBJ: FUNCTION_OBJ,
+}
+
+// TypeName returns the name of the type of obj as programs see it.
+func TypeName(obj Object) string {
+	if name, ok := typeNames[obj.Type()]; ok {
+		return name
+	}
+	return string(obj.Type())
+}
+
+func typeOf(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return &String{Value: TypeName(args[0])}
+}
+
+// isType returns a builtin reporting whether its argument has one of names.
+func isType(names ...string) BuiltinFunction {
+	return func(ctx *Context, args ...Object) Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		name := TypeName(args[0])
+		for _, n := range names {
+			if n == name {
+				return TRUE
+			}
+		}
+		return FALSE
+	}
+}
+
+// toString returns strings as they are and the inspected form of anything else.
+func toString(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if s, ok := args[0].(*String); ok {
+		return s
+	}
+	return &String{Value: args[0].Inspect()}
+}
+
+// toInteger converts decimal strings and booleans to integers.
+func toInteger(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *Integer, *BigInt:
+		return arg
+	case *Boolean:
+		if arg.Value {
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case *String:
+		value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 10)
+		if !ok {
+			return newError("could not convert %q to INTEGER", arg.Value)
+		}
+		return normalize(value)
+	default:
+		return newError("argument to `int` must be INTEGER, STRING or BOOLEAN, got %s", arg.Type())
+	}
+}
+
+// arity returns the number of arguments a function takes, or -1 for
+// builtins taking a varying number of arguments.
+func arity(ctx *Context, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch fn := args[0].(type) {
+	case *Function:
+		return &Integer{Value: int64(len(fn.Parameters))}
+	case *Closure:
+		return &Integer{Value: int64(fn.Function.NumParameters)}
+	case *Builtin:
+		return &Integer{Value: int64(fn.Arity)}
+	default:
+		return newError("argument to `arity` must be a function, got %s", fn.Type())
+	}
+}
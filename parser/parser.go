@@ -5,7 +5,9 @@ import (
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/lexer"
 	"github.com/masa-suzu/monkey/token"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -185,6 +187,11 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.currentToken}
 	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		if n, ok := new(big.Int).SetString(p.currentToken.Literal, 0); ok {
+			return &ast.BigIntegerLiteral{Token: p.currentToken, Value: n}
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as interger", p.currentToken.Literal)
 		p.errors = append(p.errors, msg)
@@ -195,7 +202,40 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
+	value, err := unescape(p.currentToken.Literal)
+	if err != nil {
+		p.errors = append(p.errors, err.Error())
+		return nil
+	}
+	return &ast.StringLiteral{Token: p.currentToken, Value: value}
+}
+
+func unescape(literal string) (string, error) {
+	if !strings.Contains(literal, "\\") {
+		return literal, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '\\' {
+			out.WriteByte(literal[i])
+			continue
+		}
+		i++
+		switch literal[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case '"', '\\':
+			out.WriteByte(literal[i])
+		default:
+			return "", fmt.Errorf("unknown escape sequence \\%c in %q", literal[i], literal)
+		}
+	}
+	return out.String(), nil
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -397,6 +437,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/lexer"
+	"strings"
 	"testing"
 )
 
@@ -572,6 +573,31 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := "9223372036854775808;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	literal, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.BigIntegerLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value.String() != "9223372036854775808" {
+		t.Errorf("literal.Value not %s. got=%s", "9223372036854775808", literal.Value)
+	}
+	if literal.String() != "9223372036854775808" {
+		t.Errorf("literal.String not %s. got=%s", "9223372036854775808", literal.String())
+	}
+}
+
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world";`
 
@@ -591,6 +617,42 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestEscapedStringLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"say \"hi\""`, `say "hi"`},
+		{`"a\nb\tc\r"`, "a\nb\tc\r"},
+		{`"back\\slash"`, `back\slash`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() not %q. got=%q", tt.input, literal.String())
+		}
+	}
+
+	p := New(lexer.New(`"\q"`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != `unknown escape sequence \q in "\\q"` {
+		t.Errorf("expected unknown escape sequence error, got=%v", p.Errors())
+	}
+}
+
 func TestParsingEmptyArrayLiterals(t *testing.T) {
 	input := "[]"
 
@@ -735,6 +797,17 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		expectedValue := expected[literal.TokenLiteral()]
 		testIntegerLiteral(t, value, expectedValue)
 	}
+
+	order := []string{}
+	for _, key := range hash.Keys {
+		order = append(order, key.String())
+	}
+	if strings.Join(order, ",") != `"one","two","three"` {
+		t.Errorf("hash.Keys is not in source order. got=%v", order)
+	}
+	if hash.String() != `{"one":1, "two":2, "three":3}` {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
 }
 
 func TestMacroLiteralParsing(t *testing.T) {
@@ -3,11 +3,10 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"github.com/masa-suzu/monkey/compiler"
 	"github.com/masa-suzu/monkey/formatter"
 	"github.com/masa-suzu/monkey/lexer"
 	"github.com/masa-suzu/monkey/parser"
-	"github.com/masa-suzu/monkey/vm"
+	"strings"
 
 	"github.com/gopherjs/gopherjs/js"
 	"github.com/masa-suzu/monkey/object"
@@ -22,13 +21,8 @@ func main() {
 
 func startRep(source string) string {
 	out := bytes.NewBufferString("")
-	constants := []object.Object{}
-	globals := make([]object.Object, vm.GlobalSize)
-	symbolTable := compiler.NewSymbolTable()
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
-	}
-	repl.Rep_VM(source, out, false, constants, globals, symbolTable)
+	ctx := object.NewContext(strings.NewReader(""), out)
+	repl.NewSession(ctx, true).Rep(source)
 	return fmt.Sprint(out)
 }
 
@@ -20,6 +20,14 @@ func TestStartRep(t *testing.T) {
 			hello();`,
 			"Hello, Monkey!\n",
 		},
+		{
+			`puts("Hello, Monkey!");`,
+			"Hello, Monkey!\nnull\n",
+		},
+		{
+			`let twice = macro(x) { quote(unquote(x) + unquote(x)) }; twice(21);`,
+			"42\n",
+		},
 	}
 
 	for _, tt := range tests {
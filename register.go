@@ -0,0 +1,115 @@
+package monkey
+
+import (
+	"fmt"
+	"github.com/masa-suzu/monkey/marshal"
+	"github.com/masa-suzu/monkey/object"
+	"reflect"
+)
+
+var (
+	contextType = reflect.TypeOf((*object.Context)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Register defines a global named name which calls the Go function fn.
+// See NewBuiltin for the functions accepted.
+func (i *Interpreter) Register(name string, fn interface{}) error {
+	builtin, err := NewBuiltin(name, fn)
+	if err != nil {
+		return err
+	}
+	i.SetGlobal(name, builtin)
+	return nil
+}
+
+// NewBuiltin wraps the Go function fn as a builtin called name.
+//
+// Parameters and results may be of any type package marshal converts.
+// fn may take a *object.Context as its first parameter,
+// and may return an error as its last result, which is reported as an error object.
+func NewBuiltin(name string, fn interface{}) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s: want a function, got %T", name, fn)
+	}
+	t := v.Type()
+
+	params := []reflect.Type{}
+	withContext := t.NumIn() > 0 && t.In(0) == contextType
+	for i := 0; i < t.NumIn(); i++ {
+		if i == 0 && withContext {
+			continue
+		}
+		in := t.In(i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			in = in.Elem()
+		}
+		if !marshal.CanConvert(in) {
+			return nil, fmt.Errorf("%s: unsupported parameter type %s", name, in)
+		}
+		params = append(params, in)
+	}
+
+	withError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	numResults := t.NumOut()
+	if withError {
+		numResults--
+	}
+	if numResults > 1 {
+		return nil, fmt.Errorf("%s: want at most one result besides error, got %d", name, numResults)
+	}
+	if numResults == 1 && !marshal.CanConvert(t.Out(0)) {
+		return nil, fmt.Errorf("%s: unsupported result type %s", name, t.Out(0))
+	}
+
+	arity := len(params)
+	if t.IsVariadic() {
+		arity = object.Variadic
+	}
+	return &object.Builtin{
+		Arity: arity,
+		Fn: func(ctx *object.Context, args ...object.Object) object.Object {
+			if t.IsVariadic() {
+				if len(args) < len(params)-1 {
+					return newError("wrong number of arguments. got=%d, want at least %d", len(args), len(params)-1)
+				}
+			} else if len(args) != len(params) {
+				return newError("wrong number of arguments. got=%d, want=%d", len(args), len(params))
+			}
+
+			in := []reflect.Value{}
+			if withContext {
+				in = append(in, reflect.ValueOf(ctx))
+			}
+			for i, arg := range args {
+				param := params[len(params)-1]
+				if i < len(params) {
+					param = params[i]
+				}
+				value, err := marshal.Convert(arg, param)
+				if err != nil {
+					return newError("argument %d to `%s` %s", i+1, name, err)
+				}
+				in = append(in, value)
+			}
+
+			out := v.Call(in)
+			if withError && !out[len(out)-1].IsNil() {
+				return newError("%s", out[len(out)-1].Interface().(error))
+			}
+			if numResults == 0 {
+				return nil
+			}
+			ret, err := marshal.FromGo(out[0].Interface())
+			if err != nil {
+				return newError("result of `%s` %s", name, err)
+			}
+			return ret
+		},
+	}, nil
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
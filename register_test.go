@@ -0,0 +1,143 @@
+package monkey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/masa-suzu/monkey/object"
+	"strings"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	funcs := map[string]interface{}{
+		"repeat": func(n int64, s string) (string, error) {
+			if n < 0 {
+				return "", errors.New("negative count")
+			}
+			return strings.Repeat(s, int(n)), nil
+		},
+		"sum": func(xs ...int) int {
+			total := 0
+			for _, x := range xs {
+				total += x
+			}
+			return total
+		},
+		"not": func(b bool) bool { return !b },
+		"evens": func(xs []int64) []int64 {
+			ret := []int64{}
+			for _, x := range xs {
+				if x%2 == 0 {
+					ret = append(ret, x)
+				}
+			}
+			return ret
+		},
+		"lookup": func(m map[string]int, k string) int { return m[k] },
+		"invert": func(m map[string]int) map[int]string {
+			ret := map[int]string{}
+			for k, v := range m {
+				ret[v] = k
+			}
+			return ret
+		},
+		"small":    func(x int8) int8 { return x },
+		"describe": func(x interface{}) string { return fmt.Sprintf("%T", x) },
+		"identity": func(x object.Object) object.Object { return x },
+		"nothing":  func() {},
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`repeat(3, "ab")`, "ababab"},
+		{`repeat(-1, "ab")`, "ERROR: negative count"},
+		{`repeat("ab", 3)`, "ERROR: argument 1 to `repeat` must be INTEGER, got STRING"},
+		{`repeat(1)`, "ERROR: wrong number of arguments. got=1, want=2"},
+		{`sum()`, "0"},
+		{`sum(1, 2, 3)`, "6"},
+		{`arity(repeat)`, "2"},
+		{`arity(sum)`, "-1"},
+		{`sum(1, true)`, "ERROR: argument 2 to `sum` must be INTEGER, got BOOLEAN"},
+		{`not(true)`, "false"},
+		{`if (not(true)) { 1 } else { 2 }`, "2"},
+		{`evens([1, 2, 3, 4])`, "[2, 4]"},
+		{`evens([1, "2"])`, "ERROR: argument 1 to `evens` must be INTEGER, got STRING"},
+		{`lookup({"one": 1, "two": 2}, "two")`, "2"},
+		{`invert({"one": 1})[1]`, "one"},
+		{`small(127)`, "127"},
+		{`small(128)`, "ERROR: argument 1 to `small` 128 overflows int8"},
+		{`describe(1)`, "int64"},
+		{`describe([1, "a"])`, "[]interface {}"},
+		{`describe({1: true})`, "map[interface {}]interface {}"},
+		{`identity(fn(x) { x })(5)`, "5"},
+		{`nothing()`, "null"},
+		{`let repeat = fn(x) { x }; repeat(1)`, "1"},
+	}
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			i := New(WithBackend(backend))
+			for name, fn := range funcs {
+				if err := i.Register(name, fn); err != nil {
+					t.Fatalf("Register(%q) got error: %s", name, err)
+				}
+			}
+
+			got, err := i.Eval(tt.input)
+			actual := ""
+			if err != nil {
+				actual = "ERROR: " + strings.Join(err.(*Error).Messages, "")
+			} else {
+				actual = got.Inspect()
+			}
+			if actual != tt.expected {
+				t.Errorf("backend %d: %s expected=%q, got=%q", backend, tt.input, tt.expected, actual)
+			}
+		}
+	}
+}
+
+func TestRegisterWithContext(t *testing.T) {
+	for _, backend := range backends {
+		out := &bytes.Buffer{}
+		i := New(WithBackend(backend), WithOutput(out))
+		err := i.Register("say", func(ctx *object.Context, s string) {
+			fmt.Fprintln(ctx.Out, "said: "+s)
+		})
+		if err != nil {
+			t.Fatalf("Register got error: %s", err)
+		}
+		if _, err := i.Eval(`say("hello")`); err != nil {
+			t.Fatalf("backend %d: got error: %s", backend, err)
+		}
+		if out.String() != "said: hello\n" {
+			t.Errorf("backend %d: expected=%q, got=%q", backend, "said: hello\n", out.String())
+		}
+	}
+}
+
+func TestNewBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       interface{}
+		expected string
+	}{
+		{1, "f: want a function, got int"},
+		{func(f float64) {}, "f: unsupported parameter type float64"},
+		{func() (int, int) { return 0, 0 }, "f: want at most one result besides error, got 2"},
+		{func() chan int { return nil }, "f: unsupported result type chan int"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewBuiltin("f", tt.fn)
+		if err == nil {
+			t.Errorf("expected error for %T", tt.fn)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
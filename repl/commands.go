@@ -0,0 +1,276 @@
+package repl
+
+import (
+	"fmt"
+	"github.com/masa-suzu/monkey/ast"
+	"github.com/masa-suzu/monkey/code"
+	"github.com/masa-suzu/monkey/compiler"
+	"github.com/masa-suzu/monkey/evaluator"
+	"github.com/masa-suzu/monkey/formatter"
+	"github.com/masa-suzu/monkey/object"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// A command controls the REPL instead of running Monkey code. It is run by
+// typing its name after a colon, followed by its argument if it takes one.
+type command struct {
+	name string
+	arg  string // how the argument is shown in :help, empty if there is none
+	help string
+	// optional commands run without their argument too.
+	optional bool
+	run      func(sh *shell, arg string)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "help", help: "list the commands", run: (*shell).help},
+		{name: "load", arg: "<file>", help: "run a file", run: (*shell).load},
+		{name: "reset", help: "forget every variable and macro", run: (*shell).reset},
+		{name: "env", help: "list the variables with their values", run: (*shell).listEnv},
+		{name: "type", arg: "<code>", help: "print the type of the value of code, forgetting its names", run: (*shell).printType},
+		{name: "ast", arg: "<code>", help: "print the syntax tree of code", run: (*shell).printAST},
+		{name: "bytecode", arg: "<code>", help: "print the instructions code compiles to", run: (*shell).printByteCode},
+		{name: "time", arg: "<code>", help: "run code and print how long it took", run: (*shell).timeCode},
+		{name: "backend", arg: "vm|eval", help: "print or change the backend", optional: true, run: (*shell).backend},
+		{name: "fmt", arg: "<code>", help: "format code, or the last line run", optional: true, run: (*shell).format},
+		{name: "expand", arg: "<code>", help: "print code with its macro calls expanded", run: (*shell).expand},
+		{name: "trace", help: "print every macro expansion, or stop", run: (*shell).trace},
+		{name: "quit", help: "leave the REPL", run: (*shell).quitCommand},
+	}
+}
+
+// command runs the command on line, which starts with a colon.
+func (sh *shell) command(line string) {
+	line = strings.TrimPrefix(strings.TrimSpace(line), ":")
+	name, arg := line, ""
+	if i := strings.IndexAny(line, " \t\n"); i >= 0 {
+		name, arg = line[:i], strings.TrimSpace(line[i:])
+	}
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if c.arg != "" && arg == "" && !c.optional {
+			fmt.Fprintf(sh.out, "usage: :%s %s\n", c.name, c.arg)
+			return
+		}
+		if c.arg == "" && arg != "" {
+			fmt.Fprintf(sh.out, "usage: :%s\n", c.name)
+			return
+		}
+		c.run(sh, arg)
+		return
+	}
+	fmt.Fprintf(sh.out, "unknown command :%s, :help lists the commands\n", name)
+}
+
+func (sh *shell) help(string) {
+	for _, c := range commands {
+		usage := ":" + c.name
+		if c.arg != "" {
+			usage += " " + c.arg
+		}
+		fmt.Fprintf(sh.out, "%-18s %s\n", usage, c.help)
+	}
+}
+
+func (sh *shell) load(path string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		printErrorsWithMonkeyFace(sh.out, []string{err.Error()}, "Load")
+		return
+	}
+	sh.Rep(string(src))
+}
+
+func (sh *shell) reset(string) {
+	sh.Reset()
+	io.WriteString(sh.out, "session reset\n")
+}
+
+// listEnv prints the macros and the variables of the backend in use, one per
+// line in the order of their names.
+func (sh *shell) listEnv(string) {
+	for _, name := range sh.macros.Names() {
+		macro, _ := sh.macros.Get(name)
+		fmt.Fprintf(sh.out, "%s = %s\n", name, macro.Inspect())
+	}
+
+	if !sh.UseVM {
+		for _, name := range sh.env.Names() {
+			value, _ := sh.env.Get(name)
+			fmt.Fprintf(sh.out, "%s = %s\n", name, inspect(value))
+		}
+		return
+	}
+	for _, name := range sh.symbolTable.Names() {
+		symbol, _ := sh.symbolTable.Resolve(name)
+		if symbol.Scope == compiler.GlobalScope {
+			fmt.Fprintf(sh.out, "%s = %s\n", name, inspect(sh.globals[symbol.Index]))
+		}
+	}
+}
+
+// printType runs in and prints the type of its value as type() reports it.
+// Names and macros it defines are forgotten, but its output is not.
+func (sh *shell) printType(in string) {
+	value, ok := sh.scratch().Eval(in)
+	if !ok {
+		return
+	}
+	fmt.Fprintln(sh.out, typeOf(value))
+}
+
+// printAST prints the nodes of the program in before its macro calls are
+// expanded, each indented below its parent.
+func (sh *shell) printAST(in string) {
+	program, ok := parse(in, sh.out)
+	if !ok {
+		return
+	}
+	depth := 0
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+		fmt.Fprintf(sh.out, "%s%s\n", strings.Repeat("  ", depth), describeNode(node))
+		depth++
+		return true
+	})
+}
+
+// printByteCode compiles in after expanding its macro calls, and prints its
+// instructions and constants without running it. Names and macros it defines
+// are forgotten.
+func (sh *shell) printByteCode(in string) {
+	program, ok := parse(in, sh.out)
+	if !ok {
+		return
+	}
+	expanded, err := evaluator.Expand(program, object.NewEnclosedEnvironment(sh.macros))
+	if err != nil {
+		printErrorsWithMonkeyFace(sh.out, []string{err.Error()}, "Macro")
+		return
+	}
+
+	c := compiler.NewWithState(sh.symbolTable.Copy(), append([]object.Object{}, sh.constants...))
+	if err := c.Compile(expanded); err != nil {
+		printErrorsWithMonkeyFace(sh.out, []string{err.Error()}, "Compile")
+		return
+	}
+
+	byteCode := c.ByteCode()
+	fmt.Fprintf(sh.out, "[instructions]\n%s", byteCode.Instructions)
+	fmt.Fprintln(sh.out, "[constants]")
+	for i, constant := range byteCode.Constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			fmt.Fprintf(sh.out, "%04d %s %s\n", i, constant.Type(), constant.Inspect())
+			continue
+		}
+		fmt.Fprintf(sh.out, "%04d %s locals=%d parameters=%d\n", i, fn.Type(), fn.NumLocals, fn.NumParameters)
+		printIndented(sh.out, fn.Instructions)
+	}
+}
+
+func printIndented(out io.Writer, ins code.Instructions) {
+	for _, line := range strings.SplitAfter(ins.String(), "\n") {
+		if line != "" {
+			io.WriteString(out, "    "+line)
+		}
+	}
+}
+
+func (sh *shell) timeCode(in string) {
+	start := time.Now()
+	value, ok := sh.Eval(in)
+	elapsed := time.Since(start)
+	if !ok {
+		return
+	}
+	printValue(sh.out, value)
+	fmt.Fprintf(sh.out, "took %s\n", elapsed)
+}
+
+func (sh *shell) backend(name string) {
+	switch name {
+	case "":
+	case "vm":
+		sh.UseVM = true
+	case "eval":
+		sh.UseVM = false
+	default:
+		io.WriteString(sh.out, "usage: :backend vm|eval\n")
+		return
+	}
+	if sh.UseVM {
+		io.WriteString(sh.out, "backend vm\n")
+	} else {
+		io.WriteString(sh.out, "backend eval\n")
+	}
+}
+
+func (sh *shell) format(in string) {
+	if in == "" {
+		in = sh.last
+	}
+	program, ok := parse(in, sh.out)
+	if !ok {
+		return
+	}
+	io.WriteString(sh.out, formatter.Format(program, 0))
+	io.WriteString(sh.out, "\n")
+}
+
+func (sh *shell) expand(in string) {
+	sh.Expand(in)
+}
+
+func (sh *shell) trace(string) {
+	sh.ToggleMacroTrace()
+}
+
+func (sh *shell) quitCommand(string) {
+	sh.quit = true
+}
+
+func inspect(value object.Object) string {
+	if value == nil {
+		return "null"
+	}
+	return value.Inspect()
+}
+
+func typeOf(value object.Object) string {
+	if value == nil {
+		return string(object.NULL_OBJ)
+	}
+	return object.TypeName(value)
+}
+
+// describeNode returns the type of node, followed by its value or operator
+// if it has one.
+func describeNode(node ast.Node) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return name + " " + node.Value
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.Boolean:
+		return name + " " + node.String()
+	case *ast.StringLiteral:
+		return fmt.Sprintf("%s %q", name, node.Value)
+	case *ast.PrefixExpression:
+		return name + " " + node.Operator
+	case *ast.InfixExpression:
+		return name + " " + node.Operator
+	}
+	return name
+}
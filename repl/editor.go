@@ -0,0 +1,349 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// editor reads lines from a terminal, letting the user move the cursor, edit
+// the line, go through the history and complete identifiers.
+//
+// Keys: left/right or ctrl-b/ctrl-f move, home/end or ctrl-a/ctrl-e jump,
+// up/down or ctrl-p/ctrl-n go through the history, backspace and delete or
+// ctrl-d remove, ctrl-k and ctrl-u cut to the end and the start, tab
+// completes, ctrl-c abandons the line and ctrl-d on an empty line ends input.
+type editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *History
+	complete func() []string
+	// raw puts the terminal in raw mode, returning a function restoring it.
+	raw func() (func(), error)
+}
+
+// lineState is a line being edited.
+type lineState struct {
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+	if e.raw != nil {
+		if restore, err := e.raw(); err == nil {
+			defer restore()
+		}
+	}
+
+	l := &lineState{prompt: prompt}
+	// browsing is the index of the history entry shown; the line being
+	// written is kept in draft while going through older ones.
+	browsing := e.history.Len()
+	draft := ""
+	e.refresh(l)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			return string(l.buf), err
+		}
+
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			line := string(l.buf)
+			e.history.Add(line)
+			return line, nil
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(l.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			l.deleteForward()
+		case keyCtrlA:
+			l.pos = 0
+		case keyCtrlE:
+			l.pos = len(l.buf)
+		case keyCtrlB:
+			l.move(-1)
+		case keyCtrlF:
+			l.move(1)
+		case keyBackspace, keyDelete:
+			l.deleteBackward()
+		case keyCtrlK:
+			l.buf = l.buf[:l.pos]
+		case keyCtrlU:
+			l.buf = l.buf[l.pos:]
+			l.pos = 0
+		case keyCtrlP:
+			browsing, draft = e.browse(l, browsing, -1, draft)
+		case keyCtrlN:
+			browsing, draft = e.browse(l, browsing, 1, draft)
+		case keyTab:
+			e.completeWord(l)
+		case keyEscape:
+			switch e.readEscape() {
+			case 'A':
+				browsing, draft = e.browse(l, browsing, -1, draft)
+			case 'B':
+				browsing, draft = e.browse(l, browsing, 1, draft)
+			case 'C':
+				l.move(1)
+			case 'D':
+				l.move(-1)
+			case 'H':
+				l.pos = 0
+			case 'F':
+				l.pos = len(l.buf)
+			case '~':
+				l.deleteForward()
+			}
+		default:
+			if r >= ' ' {
+				l.insert(r)
+			}
+		}
+		e.refresh(l)
+	}
+}
+
+// readEscape reads the rest of an escape sequence, returning the key it
+// stands for: A to D for the arrows, H and F for home and end, and ~ for
+// delete. Other sequences are read and ignored.
+func (e *editor) readEscape() rune {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0
+	}
+	digits := ""
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if r < '0' || r > '9' {
+			break
+		}
+		digits += string(r)
+	}
+	if r != '~' {
+		return r
+	}
+	switch digits {
+	case "1", "7":
+		return 'H'
+	case "4", "8":
+		return 'F'
+	case "3":
+		return '~'
+	}
+	return 0
+}
+
+// browse shows the history entry step entries away from the one at index.
+func (e *editor) browse(l *lineState, index, step int, draft string) (int, string) {
+	next := index + step
+	if next < 0 || next > e.history.Len() {
+		return index, draft
+	}
+	if index == e.history.Len() {
+		draft = string(l.buf)
+	}
+	if next == e.history.Len() {
+		l.set(draft)
+	} else {
+		l.set(e.history.At(next))
+	}
+	return next, draft
+}
+
+// completeWord completes the identifier before the cursor, listing the
+// candidates if there is more than one.
+func (e *editor) completeWord(l *lineState) {
+	start := l.pos
+	for start > 0 && isIdentifierRune(l.buf[start-1]) {
+		start--
+	}
+	prefix := string(l.buf[start:l.pos])
+	if prefix == "" || e.complete == nil {
+		return
+	}
+
+	candidates := completions(prefix, e.complete())
+	if len(candidates) == 0 {
+		io.WriteString(e.out, "\a")
+		return
+	}
+	common := commonPrefix(candidates)
+	if len(common) > len(prefix) {
+		for _, r := range common[len(prefix):] {
+			l.insert(r)
+		}
+		return
+	}
+	if len(candidates) > 1 {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func (e *editor) refresh(l *lineState) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", l.prompt, string(l.buf))
+	if back := len(l.buf) - l.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (l *lineState) insert(r rune) {
+	l.buf = append(l.buf, 0)
+	copy(l.buf[l.pos+1:], l.buf[l.pos:])
+	l.buf[l.pos] = r
+	l.pos++
+}
+
+func (l *lineState) deleteBackward() {
+	if l.pos == 0 {
+		return
+	}
+	l.buf = append(l.buf[:l.pos-1], l.buf[l.pos:]...)
+	l.pos--
+}
+
+func (l *lineState) deleteForward() {
+	if l.pos == len(l.buf) {
+		return
+	}
+	l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+}
+
+func (l *lineState) move(step int) {
+	if pos := l.pos + step; pos >= 0 && pos <= len(l.buf) {
+		l.pos = pos
+	}
+}
+
+func (l *lineState) set(s string) {
+	l.buf = []rune(s)
+	l.pos = len(l.buf)
+}
+
+func isIdentifierRune(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_'
+}
+
+// completions returns the sorted names starting with prefix, without
+// duplicates.
+func completions(prefix string, names []string) []string {
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func commonPrefix(names []string) string {
+	common := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	return common
+}
+
+// maxHistory is the number of lines History keeps.
+const maxHistory = 1000
+
+// History holds the lines entered in the REPL. Lines are appended to its
+// file, if it has one, so that they are available the next time.
+type History struct {
+	path  string
+	lines []string
+}
+
+// LoadHistory reads the history in the file at path, which need not exist,
+// and cuts the file down to the last maxHistory lines if it grew longer.
+// An empty path gives a history which is not saved.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if path == "" {
+		return h, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[len(h.lines)-maxHistory:]
+		if err := os.WriteFile(path, []byte(strings.Join(h.lines, "\n")+"\n"), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func (h *History) Len() int {
+	return len(h.lines)
+}
+
+// At returns the ith line, the oldest being the 0th.
+func (h *History) At(i int) string {
+	return h.lines[i]
+}
+
+// Add appends line unless it is empty or the same as the last line.
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return nil
+	}
+	h.lines = append(h.lines, line)
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[1:]
+	}
+	if h.path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.WriteString(f, line+"\n")
+	return err
+}
@@ -0,0 +1,219 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string, history *History, names ...string) *editor {
+	return &editor{
+		in:       bufio.NewReader(strings.NewReader(input)),
+		out:      &bytes.Buffer{},
+		history:  history,
+		complete: func() []string { return names },
+	}
+}
+
+func TestEditorKeys(t *testing.T) {
+	const (
+		left  = "\x1b[D"
+		right = "\x1b[C"
+		home  = "\x1b[H"
+		end   = "\x1b[F"
+		del   = "\x1b[3~"
+	)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1;\r", "let x = 1;"},
+		{"ac" + left + "b\r", "abc"},
+		{"ac" + left + left + right + "b\r", "abc"},
+		{"bc" + home + "a" + end + "d\r", "abcd"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"abc\x7f\x7fd\r", "ad"},
+		{"abc" + left + left + del + "\r", "ac"},
+		{"abc\x02\x02\x04\r", "ac"},
+		{"abcd\x02\x02\x0b\r", "ab"},
+		{"abcd\x02\x02\x15\r", "cd"},
+		{"añb" + left + "\x7f\r", "ab"},
+		{"a\x1b[5~b\r", "ab"},
+	}
+
+	for _, tt := range tests {
+		e := newTestEditor(tt.input, &History{})
+		line, err := e.ReadLine(">> ")
+		if err != nil {
+			t.Errorf("%q got error: %s", tt.input, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%q expected=%q, got=%q", tt.input, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorInterruptAndEOF(t *testing.T) {
+	e := newTestEditor("abc\x03\x04", &History{})
+	if _, err := e.ReadLine(""); err != errInterrupted {
+		t.Errorf("expected errInterrupted, got=%v", err)
+	}
+	if _, err := e.ReadLine(""); err != io.EOF {
+		t.Errorf("expected io.EOF, got=%v", err)
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	const (
+		up   = "\x1b[A"
+		down = "\x1b[B"
+	)
+	history := &History{}
+	e := newTestEditor("first\rsecond\r"+up+up+"\r"+up+down+"x"+"\r"+"draft"+up+down+"\r", history)
+
+	expected := []string{"first", "second", "first", "x", "draft"}
+	for _, want := range expected {
+		line, err := e.ReadLine("")
+		if err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		if line != want {
+			t.Errorf("expected=%q, got=%q", want, line)
+		}
+	}
+
+	if !reflect.DeepEqual(history.lines, []string{"first", "second", "first", "x", "draft"}) {
+		t.Errorf("wrong history: %q", history.lines)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history")
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, line := range []string{"let a = 1;", "", "a", "a"} {
+		if err := history.Add(line); err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+	}
+
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.lines, []string{"let a = 1;", "a"}) {
+		t.Errorf("wrong history: %q", loaded.lines)
+	}
+
+	e := newTestEditor("\x1b[A\x1b[A\r", loaded)
+	if line, _ := e.ReadLine(""); line != "let a = 1;" {
+		t.Errorf("expected the first line of the last session, got=%q", line)
+	}
+}
+
+func TestHistoryFileIsTruncated(t *testing.T) {
+	dir, err := os.MkdirTemp("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history")
+
+	var data strings.Builder
+	for i := 0; i < maxHistory+10; i++ {
+		fmt.Fprintf(&data, "%d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(data.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if history.Len() != maxHistory || history.At(0) != "10" {
+		t.Errorf("wrong history: %d lines from %q", history.Len(), history.At(0))
+	}
+	if err := history.Add("new"); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(written), "\n"), "\n")
+	if len(lines) != maxHistory+1 || lines[0] != "10" || lines[len(lines)-1] != "new" {
+		t.Errorf("history file is not truncated: %d lines from %q", len(lines), lines[0])
+	}
+}
+
+func TestEditorCompletion(t *testing.T) {
+	names := []string{"len", "let_me", "length", "puts", "push"}
+	tests := []struct {
+		input    string
+		expected string
+		listed   string
+	}{
+		{"pu\t\r", "pu", "push  puts"},
+		{"pus\t\r", "push", ""},
+		{"x + le\tn\t\r", "x + len", "len  length"},
+		{"lengt\t(1)\r", "length(1)", ""},
+		{"q\t\r", "q", ""},
+	}
+
+	for _, tt := range tests {
+		e := newTestEditor(tt.input, &History{}, names...)
+		line, err := e.ReadLine("")
+		if err != nil {
+			t.Errorf("%q got error: %s", tt.input, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%q expected=%q, got=%q", tt.input, tt.expected, line)
+		}
+		out := e.out.(*bytes.Buffer).String()
+		if tt.listed != "" && !strings.Contains(out, "\r\n"+tt.listed+"\r\n") {
+			t.Errorf("%q candidates are not listed: %q", tt.input, out)
+		}
+	}
+}
+
+func TestNeedsMore(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 + 1", false},
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) {\n  x\n}", false},
+		{"[1, 2,", true},
+		{"puts(", true},
+		{`"{"`, false},
+		{`"abc`, true},
+		{`"a\"{"`, false},
+		{"}", false},
+	}
+
+	for _, tt := range tests {
+		if got := needsMore(tt.input); got != tt.expected {
+			t.Errorf("needsMore(%q) expected=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
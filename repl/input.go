@@ -0,0 +1,120 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// A lineReader reads a line of input after writing prompt.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// errInterrupted is returned by ReadLine when the user abandons a line.
+var errInterrupted = errors.New("interrupted")
+
+// plainReader reads lines without editing them, for input which is not a
+// terminal.
+type plainReader struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	line, err := r.in.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// newLineReader returns an editor if in and out are a terminal, and a
+// plainReader otherwise. names lists the identifiers to complete.
+func newLineReader(in io.Reader, reader *bufio.Reader, out io.Writer, names func() []string) lineReader {
+	inFile, ok := in.(*os.File)
+	if !ok || !isTerminal(inFile.Fd()) {
+		return &plainReader{in: reader, out: out}
+	}
+	if outFile, ok := out.(*os.File); !ok || !isTerminal(outFile.Fd()) {
+		return &plainReader{in: reader, out: out}
+	}
+
+	history, err := LoadHistory(historyPath())
+	if err != nil {
+		history = &History{}
+	}
+	return &editor{
+		in:       reader,
+		out:      out,
+		history:  history,
+		complete: names,
+		raw:      func() (func(), error) { return makeRaw(inFile.Fd()) },
+	}
+}
+
+// historyPath returns $MONKEY_HISTORY, defaulting to ~/.monkey_history.
+func historyPath() string {
+	if path := os.Getenv("MONKEY_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home + string(os.PathSeparator) + ".monkey_history"
+}
+
+// readInput reads lines until the brackets and strings they open are closed,
+// prompting for the lines after the first with continuation.
+func readInput(r lineReader, prompt, continuation string) (string, error) {
+	lines := []string{}
+	p := prompt
+	for {
+		line, err := r.ReadLine(p)
+		if err == errInterrupted {
+			return "", err
+		}
+		if err != nil && line == "" {
+			if len(lines) == 0 {
+				return "", err
+			}
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+		src := strings.Join(lines, "\n")
+		if err != nil || !needsMore(src) {
+			return src, nil
+		}
+		p = continuation
+	}
+}
+
+// needsMore reports whether src ends inside a string or with brackets left
+// open.
+func needsMore(src string) bool {
+	depth := 0
+	inString := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '{' || c == '[':
+			depth++
+		case c == ')' || c == '}' || c == ']':
+			depth--
+		}
+	}
+	return inString || depth > 0
+}
+
+// continuationPrompt returns prompt with dots in place of its other characters,
+// so ">> " becomes ".. ".
+func continuationPrompt(prompt string) string {
+	trimmed := strings.TrimRight(prompt, " ")
+	return strings.Repeat(".", len([]rune(trimmed))) + prompt[len(trimmed):]
+}
@@ -3,97 +3,67 @@ package repl
 import (
 	"bufio"
 	"fmt"
-	"github.com/masa-suzu/monkey/compiler"
-	"github.com/masa-suzu/monkey/evaluator"
+	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/lexer"
 	"github.com/masa-suzu/monkey/object"
 	"github.com/masa-suzu/monkey/parser"
-	"github.com/masa-suzu/monkey/vm"
 	"io"
+	"strings"
 )
 
 func Start(in io.Reader, out io.Writer, prompt string, useVM bool, debugMode bool) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
-	macroEnv := object.NewEnvironment()
-	constants := []object.Object{}
-	globals := make([]object.Object, vm.GlobalSize)
-	symbolTable := compiler.NewSymbolTable()
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
-	}
-	for {
-		fmt.Printf(prompt)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+	reader := bufio.NewReader(in)
+	session := NewSession(object.NewContext(reader, out), useVM)
+	session.DebugMode = debugMode
+	sh := &shell{Session: session, out: out}
+	lines := newLineReader(in, reader, out, session.Names)
+	continuation := continuationPrompt(prompt)
+	for !sh.quit {
+		line, err := readInput(lines, prompt, continuation)
+		if err == errInterrupted {
+			continue
 		}
-		line := scanner.Text()
-		if useVM {
-			Rep_VM(line, out, debugMode, constants, globals, symbolTable)
-		} else {
-			Rep(line, out, env, macroEnv)
+		if line == "" && err != nil {
+			return
 		}
+		sh.execute(line)
 	}
 }
 
-func Rep(in string, out io.Writer, env *object.Environment, macros *object.Environment) {
-	l := lexer.New(in)
-	p := parser.New(l)
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		printErrorsWithMonkeyFace(out, p.Errors(), "Parser")
-		return
-	}
-
-	evaluator.DefineMacros(program, macros)
-	expanded := evaluator.ExpandMacros(program, macros)
-
-	evaluated := evaluator.Eval(expanded, env)
+// shell runs the lines entered in the REPL in a Session, and the commands
+// among them.
+type shell struct {
+	*Session
+	out io.Writer
+	// last is the last line run, for :fmt.
+	last string
+	quit bool
+}
 
-	if evaluated != nil {
-		if evaluated.Type() == object.ERROR_OBJ {
-			printErrorsWithMonkeyFace(out, []string{evaluated.Inspect()}, "Run time")
-			return
-		}
-		io.WriteString(out, evaluated.Inspect())
-		io.WriteString(out, "\n")
+// execute runs line, which is a command if it starts with a colon.
+func (sh *shell) execute(line string) {
+	if strings.HasPrefix(strings.TrimSpace(line), ":") {
+		sh.command(line)
+		return
 	}
+	sh.last = line
+	sh.Rep(line)
 }
 
-func Rep_VM(in string, out io.Writer, debugMode bool, constants []object.Object, scope []object.Object, st *compiler.SymbolTable) {
-	l := lexer.New(in)
-	p := parser.New(l)
+// parse parses in, printing the errors found.
+func parse(in string, out io.Writer) (*ast.Program, bool) {
+	p := parser.New(lexer.New(in))
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
 		printErrorsWithMonkeyFace(out, p.Errors(), "Parser")
-		return
-	}
-
-	c := compiler.NewWithState(st, constants)
-
-	err := c.Compile(program)
-
-	if err != nil {
-		printErrorsWithMonkeyFace(out, []string{err.Error()}, "Compile")
-		return
-	}
-
-	var vMachine *vm.VirtualMachine
-	code := c.ByteCode()
-	constants = code.Constants
-	vMachine = vm.NewWithGlobalScope(code, scope)
-	vMachine.DebugMode = debugMode
-	err = vMachine.Run()
-
-	if err != nil {
-		printErrorsWithMonkeyFace(out, []string{err.Error()}, "Run time")
-		return
+		return nil, false
 	}
+	return program, true
+}
 
-	lastPopped := vMachine.LastPoppedStackElement()
-	if lastPopped != nil {
-		io.WriteString(out, lastPopped.Inspect())
+func printValue(out io.Writer, value object.Object) {
+	if value != nil {
+		io.WriteString(out, value.Inspect())
 		io.WriteString(out, "\n")
 	}
 }
@@ -2,6 +2,8 @@ package repl
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -30,7 +32,11 @@ func TestStart(t *testing.T) {
 		},
 		{
 			"puts(\"monkey\");",
-			"null\n",
+			"monkey\nnull\n",
+		},
+		{
+			"let name = gets();\nmonkey\nname",
+			"monkey\n",
 		},
 	}
 
@@ -55,6 +61,13 @@ func TestStartVM(t *testing.T) {
 		{"1-3", "-2\n"},
 		{"1*4", "4\n"},
 		{"1/5", "0\n"},
+		{"puts(1, 2)", "1\n2\nnull\n"},
+		{"let name = gets();\nmonkey\nname", "monkey\n"},
+		{"let c = 5;\nlet f = fn() {};\nc", "5\n"},
+		{
+			"let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };\nunless(1 > 2, 10, 20)",
+			"10\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -69,6 +82,149 @@ func TestStartVM(t *testing.T) {
 	}
 }
 
+func TestMultiLineInput(t *testing.T) {
+	input := `let add = fn(x, y) {
+  x + y
+};
+add(1,
+  2)
+"{"
+[1,
+`
+	expected := []string{">> .. 3\n", "\n>> {\n>> .. ", "expected next token to be ], got EOF instead."}
+
+	for _, useVM := range []bool{false, true} {
+		w := &fakeWriter{Buffer: bytes.NewBuffer(nil)}
+		Start(strings.NewReader(input), w, ">> ", useVM, false)
+		for _, want := range expected {
+			if !strings.Contains(w.String(), want) {
+				t.Errorf("vm=%t: expected %q in %q", useVM, want, w.String())
+			}
+		}
+	}
+}
+
+func TestMacroCommands(t *testing.T) {
+	input := `let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };
+:expand unless(x > 1, puts(x), 0)
+:trace
+unless(false, 1, 2)
+:trace
+unless(false, 1, 2)
+`
+	expected := `if((!(x > 1))) {
+    puts(x);
+} else {
+    0;
+};
+macro trace on
+expand unless(false, 1, 2)
+	c: false
+	a: 1
+	b: 2
+	=> if ((!false)) {
+    1;
+} else {
+    2;
+}
+1
+macro trace off
+1
+`
+
+	for _, useVM := range []bool{false, true} {
+		w := &fakeWriter{Buffer: bytes.NewBuffer(nil)}
+		Start(strings.NewReader(input), w, "", useVM, false)
+		if w.String() != expected {
+			t.Errorf("vm=%t: expected=%q, got=%q", useVM, expected, w.String())
+		}
+	}
+}
+
+func TestEnvCommand(t *testing.T) {
+	input := `let a = 1;
+let b = "monkey";
+let m = macro(x) { x };
+:env
+:reset
+:env`
+	expected := "m = macro(x) \nx;\n)\na = 1\nb = monkey\nsession reset\n"
+
+	for _, useVM := range []bool{false, true} {
+		w := &fakeWriter{Buffer: bytes.NewBuffer(nil)}
+		Start(strings.NewReader(input), w, "", useVM, false)
+		if w.String() != expected {
+			t.Errorf("vm=%t: expected=%q, got=%q", useVM, expected, w.String())
+		}
+	}
+}
+
+func TestTimeCommand(t *testing.T) {
+	for _, useVM := range []bool{false, true} {
+		w := &fakeWriter{Buffer: bytes.NewBuffer(nil)}
+		Start(strings.NewReader(":time 1 + 1"), w, "", useVM, false)
+		if !strings.HasPrefix(w.String(), "2\ntook ") {
+			t.Errorf("vm=%t: got=%q", useVM, w.String())
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir, err := os.MkdirTemp("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "double.mk")
+	if err := os.WriteFile(script, []byte("let double = fn(x) { x * 2 };\ndouble(21)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":type 1 + 1", "INTEGER\n"},
+		{":type \"monkey\"", "STRING\n"},
+		{":type if (false) { 1 }", "NULL\n"},
+		{":type fn(x) { x }", "FUNCTION\n"},
+		{":type len", "BUILTIN\n"},
+		{":type 123456789012345678901234567890", "INTEGER\n"},
+		{"let a = 1;\n:type let a = \"x\"; let b = 2; a\na\n:env", "STRING\n1\na = 1\n"},
+		{":ast -a + 1", "Program\n  ExpressionStatement\n    InfixExpression +\n      PrefixExpression -\n        Identifier a\n      IntegerLiteral 1\n"},
+		{":bytecode 1 + 2", "[instructions]\n0000 Constant 0\n0003 Constant 1\n0006 Add\n0007 Pop\n[constants]\n0000 INTEGER 1\n0001 INTEGER 2\n"},
+		{
+			":bytecode fn(x) { x }",
+			"[instructions]\n0000 Closure 0 0\n0004 Pop\n[constants]\n0000 COMPILED_FUNCTION locals=1 parameters=1\n    0000 GetLocal 0\n    0002 ReturnValue\n",
+		},
+		{":bytecode let y = 1\n:env", "[instructions]\n0000 Constant 0\n0003 SetGlobal 0\n[constants]\n0000 INTEGER 1\n"},
+		{":fmt if(true){1}", "if(true) {\n    1;\n};\n"},
+		{"1 + 1\n:fmt", "2\n(1 + 1);\n"},
+		{":load " + script + "\ndouble(1)", "42\n2\n"},
+		{":load", "usage: :load <file>\n"},
+		{":reset 1", "usage: :reset\n"},
+		{":backend", "backend eval\n"},
+		{":backend vm\n1 + 1", "backend vm\n2\n"},
+		{":backend lisp", "usage: :backend vm|eval\n"},
+		{":nope", "unknown command :nope, :help lists the commands\n"},
+		{":quit\n1", ""},
+	}
+
+	for _, tt := range tests {
+		for _, useVM := range []bool{false, true} {
+			expected := tt.expected
+			if useVM {
+				expected = strings.Replace(expected, "backend eval", "backend vm", 1)
+			}
+			w := &fakeWriter{Buffer: bytes.NewBuffer(nil)}
+			Start(strings.NewReader(tt.input), w, "", useVM, false)
+			if got := w.String(); got != expected {
+				t.Errorf("vm=%t %q: expected=%q, got=%q", useVM, tt.input, expected, got)
+			}
+		}
+	}
+}
+
 type fakeWriter struct {
 	Buffer *bytes.Buffer
 }
@@ -0,0 +1,172 @@
+package repl
+
+import (
+	"github.com/masa-suzu/monkey/compiler"
+	"github.com/masa-suzu/monkey/evaluator"
+	"github.com/masa-suzu/monkey/formatter"
+	"github.com/masa-suzu/monkey/object"
+	"github.com/masa-suzu/monkey/vm"
+	"io"
+)
+
+// Session holds what the inputs run one after another share: the variables
+// and macros they define and, for the VM, the symbol table, constants and
+// globals of the code compiled so far. Values and errors are printed to the
+// Out of its Context.
+type Session struct {
+	// UseVM runs inputs on the VM instead of the evaluator. Each backend
+	// keeps its own variables, while macros are shared.
+	UseVM bool
+	// DebugMode dumps the instructions and globals of the VM after each input.
+	DebugMode bool
+
+	ctx *object.Context
+
+	env    *object.Environment
+	macros *object.Environment
+
+	constants   []object.Object
+	globals     []object.Object
+	symbolTable *compiler.SymbolTable
+}
+
+func NewSession(ctx *object.Context, useVM bool) *Session {
+	s := &Session{UseVM: useVM, ctx: ctx}
+	s.Reset()
+	return s
+}
+
+// Reset forgets every variable and macro defined.
+func (s *Session) Reset() {
+	s.env = object.NewEnvironmentWithContext(s.ctx)
+	s.macros = object.NewEnvironmentWithContext(s.ctx)
+	s.ctx.Macros = evaluator.MacroExpander(s.macros)
+
+	s.constants = []object.Object{}
+	s.globals = make([]object.Object, vm.GlobalSize)
+	s.symbolTable = compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		s.symbolTable.DefineBuiltin(i, v.Name)
+	}
+}
+
+func (s *Session) Context() *object.Context {
+	return s.ctx
+}
+
+// Names returns the names of the macros, and the variables and builtins of
+// the backend in use.
+func (s *Session) Names() []string {
+	names := s.macros.Names()
+	if s.UseVM {
+		return append(names, s.symbolTable.Names()...)
+	}
+	names = append(names, s.env.Names()...)
+	for _, b := range object.Builtins {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// Rep runs in and prints its value.
+func (s *Session) Rep(in string) {
+	if value, ok := s.Eval(in); ok {
+		printValue(s.ctx.Out, value)
+	}
+}
+
+// Eval runs in and returns its value, which is nil if in ends with a let
+// statement. Errors are printed, and ok is false if there
+// were some.
+func (s *Session) Eval(in string) (value object.Object, ok bool) {
+	out := s.ctx.Out
+	program, ok := parse(in, out)
+	if !ok {
+		return nil, false
+	}
+
+	expanded, err := evaluator.Expand(program, s.macros)
+	if err != nil {
+		printErrorsWithMonkeyFace(out, []string{err.Error()}, "Macro")
+		return nil, false
+	}
+
+	if !s.UseVM {
+		evaluated := evaluator.Eval(expanded, s.env)
+		if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+			printErrorsWithMonkeyFace(out, []string{evaluated.Inspect()}, "Run time")
+			return nil, false
+		}
+		return evaluated, true
+	}
+
+	c := compiler.NewWithState(s.symbolTable, s.constants)
+
+	err = c.Compile(expanded)
+
+	if err != nil {
+		printErrorsWithMonkeyFace(out, []string{err.Error()}, "Compile")
+		return nil, false
+	}
+
+	var vMachine *vm.VirtualMachine
+	code := c.ByteCode()
+	s.constants = code.Constants
+	vMachine = vm.NewWithGlobalScope(code, s.globals)
+	vMachine.DebugMode = s.DebugMode
+	vMachine.Context = s.ctx
+	err = vMachine.Run()
+
+	if err != nil {
+		printErrorsWithMonkeyFace(out, []string{err.Error()}, "Run time")
+		return nil, false
+	}
+
+	if !compiler.ProducesValue(expanded) {
+		return nil, true
+	}
+	return vMachine.LastPoppedStackElement(), true
+}
+
+// scratch returns a session seeing the variables and macros of s, whose
+// inputs leave s unchanged.
+func (s *Session) scratch() *Session {
+	return &Session{
+		UseVM:       s.UseVM,
+		ctx:         s.ctx,
+		env:         object.NewEnclosedEnvironment(s.env),
+		macros:      object.NewEnclosedEnvironment(s.macros),
+		constants:   append([]object.Object{}, s.constants...),
+		globals:     append([]object.Object{}, s.globals...),
+		symbolTable: s.symbolTable.Copy(),
+	}
+}
+
+// Expand prints the program in with every macro call expanded, giving gensyms
+// names that can be parsed. Macros it defines are forgotten afterwards.
+func (s *Session) Expand(in string) {
+	out := s.ctx.Out
+	program, ok := parse(in, out)
+	if !ok {
+		return
+	}
+
+	expanded, err := evaluator.Expand(program, object.NewEnclosedEnvironment(s.macros))
+	if err != nil {
+		printErrorsWithMonkeyFace(out, []string{err.Error()}, "Macro")
+		return
+	}
+	io.WriteString(out, formatter.Format(object.ReadableGensyms(expanded), 0))
+	io.WriteString(out, "\n")
+}
+
+// ToggleMacroTrace switches printing each macro expansion on or off.
+func (s *Session) ToggleMacroTrace() {
+	if s.ctx.MacroTrace != nil {
+		s.ctx.MacroTrace = nil
+		io.WriteString(s.ctx.Out, "macro trace off\n")
+		return
+	}
+	s.ctx.MacroTrace = s.ctx.Out
+	io.WriteString(s.ctx.Out, "macro trace on\n")
+}
@@ -0,0 +1,115 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/masa-suzu/monkey/object"
+)
+
+func TestSessionKeepsStateAcrossInputs(t *testing.T) {
+	inputs := []struct {
+		input    string
+		expected string
+	}{
+		{"let double = fn(x) { x * 2 };", ""},
+		{"double(21)", "42"},
+		{`let greet = fn(name) { "Hello, " + name };`, ""},
+		{`greet("monkey")`, "Hello, monkey"},
+		{"let pair = [100, 200];", ""},
+		{"pair[1] + double(3)", "206"},
+		{`{"key": double(5)}["key"]`, "10"},
+		{"let twice = macro(x) { quote(unquote(x) + unquote(x)) };", ""},
+		{"twice(double(1))", "4"},
+	}
+
+	for _, useVM := range []bool{false, true} {
+		out := &bytes.Buffer{}
+		s := NewSession(object.NewContext(strings.NewReader(""), out), useVM)
+		for _, tt := range inputs {
+			value, ok := s.Eval(tt.input)
+			if !ok {
+				t.Fatalf("vm=%t %q failed: %s", useVM, tt.input, out.String())
+			}
+			if tt.expected == "" {
+				if value != nil {
+					t.Errorf("vm=%t %q: expected no value, got=%s", useVM, tt.input, value.Inspect())
+				}
+				continue
+			}
+			if value == nil || value.Inspect() != tt.expected {
+				t.Errorf("vm=%t %q: expected=%q, got=%v", useVM, tt.input, tt.expected, value)
+			}
+		}
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		label string
+	}{
+		{"let = 1", "Parser errors:"},
+		{"let m = macro() { 1 }; m(1)", "Macro errors:"},
+		{"1 + true", "Run time errors:"},
+	}
+
+	for _, useVM := range []bool{false, true} {
+		for _, tt := range tests {
+			out := &bytes.Buffer{}
+			s := NewSession(object.NewContext(strings.NewReader(""), out), useVM)
+			if _, ok := s.Eval(tt.input); ok {
+				t.Errorf("vm=%t %q did not fail", useVM, tt.input)
+			}
+			if !strings.Contains(out.String(), tt.label) {
+				t.Errorf("vm=%t %q: expected %q in %q", useVM, tt.input, tt.label, out.String())
+			}
+		}
+	}
+
+	out := &bytes.Buffer{}
+	s := NewSession(object.NewContext(strings.NewReader(""), out), true)
+	if _, ok := s.Eval("x"); ok || !strings.Contains(out.String(), "Compile errors:") {
+		t.Errorf("undefined variable did not fail to compile: %q", out.String())
+	}
+}
+
+func TestSessionReset(t *testing.T) {
+	for _, useVM := range []bool{false, true} {
+		out := &bytes.Buffer{}
+		s := NewSession(object.NewContext(strings.NewReader(""), out), useVM)
+		s.Rep("let x = 1; let m = macro() { quote(2) };")
+		s.Reset()
+
+		names := s.Names()
+		for _, name := range []string{"x", "m"} {
+			for _, n := range names {
+				if n == name {
+					t.Errorf("vm=%t: %s is defined after Reset", useVM, name)
+				}
+			}
+		}
+		s.Rep("let x = 3; x")
+		if !strings.HasSuffix(out.String(), "3\n") {
+			t.Errorf("vm=%t: got=%q", useVM, out.String())
+		}
+	}
+}
+
+func TestSessionNames(t *testing.T) {
+	for _, useVM := range []bool{false, true} {
+		s := NewSession(object.NewContext(strings.NewReader(""), &bytes.Buffer{}), useVM)
+		s.Rep("let answer = 42; let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };")
+
+		got := map[string]bool{}
+		for _, name := range s.Names() {
+			got[name] = true
+		}
+		for _, want := range []string{"answer", "unless", "len", "puts"} {
+			if !got[want] {
+				t.Errorf("vm=%t: %s is not in %q", useVM, want, s.Names())
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build (darwin || freebsd || netbsd || openbsd) && !js
+// +build darwin freebsd netbsd openbsd
+// +build !js
+
+package repl
+
+import (
+	"syscall"
+)
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
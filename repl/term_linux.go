@@ -0,0 +1,13 @@
+//go:build !js
+// +build !js
+
+package repl
+
+import (
+	"syscall"
+)
+
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
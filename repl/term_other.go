@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd) || js
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd js
+
+package repl
+
+import (
+	"errors"
+)
+
+// Terminals can not be put in raw mode here, so input is read line by line
+// without editing.
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}
@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 	"github.com/masa-suzu/monkey/code"
 	"github.com/masa-suzu/monkey/compiler"
@@ -11,12 +12,13 @@ const StackSize = 2048
 const GlobalSize = 65536
 const MaxFrames = 1024
 
-var True = &object.Boolean{Value: true}
-var False = &object.Boolean{Value: false}
-var Null = &object.Null{}
+var True = object.TRUE
+var False = object.FALSE
+var Null = object.NULL
 
 type VirtualMachine struct {
 	DebugMode bool
+	Context   *object.Context
 	constants []object.Object
 
 	stack      []object.Object
@@ -24,6 +26,13 @@ type VirtualMachine struct {
 	globals    []object.Object
 	frames     []*Frame
 	frameIndex int
+
+	// callErr holds an error raised while a builtin called back into the program.
+	callErr error
+	// caller is the copy of Context builtins get, letting them call back
+	// into the program. It is made again if Context is replaced.
+	caller     *object.Context
+	callerBase *object.Context
 }
 
 func New(byteCode *compiler.ByteCode) *VirtualMachine {
@@ -42,6 +51,7 @@ func New(byteCode *compiler.ByteCode) *VirtualMachine {
 		frameIndex: 1,
 
 		DebugMode: false,
+		Context:   object.NewStdContext(),
 	}
 }
 
@@ -59,11 +69,46 @@ func (vm *VirtualMachine) StackTop() object.Object {
 }
 
 func (vm *VirtualMachine) Run() error {
+	err := vm.run(0)
+	if err == nil && vm.DebugMode {
+		vm.dump()
+	}
+	return err
+}
+
+// Call runs fn with args to completion on top of the current stack and returns
+// its result. Builtins use it through their Context to call back into the
+// program while it runs.
+func (vm *VirtualMachine) Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	depth, sp := vm.frameIndex, vm.sp
+	if err := vm.push(fn); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := vm.push(arg); err != nil {
+			return nil, err
+		}
+	}
+	if err := vm.executeCall(len(args)); err != nil {
+		return nil, err
+	}
+	if vm.frameIndex > depth {
+		if err := vm.run(depth); err != nil {
+			return nil, err
+		}
+	}
+	result := vm.pop()
+	vm.sp = sp
+	return result, nil
+}
+
+// run executes instructions until the frames above depth have returned.
+func (vm *VirtualMachine) run(depth int) error {
 	var ip int
 	var ins code.Instructions
 	var op code.OperandCode
 
-	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+	for vm.frameIndex > depth && vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
 		vm.currentFrame().ip++
 		ip = vm.currentFrame().ip
 		ins = vm.currentFrame().Instructions()
@@ -185,15 +230,15 @@ func (vm *VirtualMachine) Run() error {
 			frame := vm.popFrame()
 
 			vm.sp = frame.basePointer - 1
-			if vm.sp < 0{
+			if vm.sp < 0 {
 				vm.sp = 0
 				vm.frameIndex = 1
 				return nil
-			} 
+			}
 			err := vm.push(ret)
 			if err != nil {
 			}
-		
+
 		case code.Return:
 			frame := vm.popFrame()
 			vm.sp = frame.basePointer - 1
@@ -249,9 +294,6 @@ func (vm *VirtualMachine) Run() error {
 			}
 		}
 	}
-	if vm.DebugMode {
-		vm.dump()
-	}
 	return nil
 }
 
@@ -283,8 +325,8 @@ func (vm *VirtualMachine) executeBinaryOperation(op code.OperandCode) error {
 	rightType := r.Type()
 	leftType := l.Type()
 
-	if leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ {
-		return vm.executeBinaryIntegerOperation(op, l.(*object.Integer), r.(*object.Integer))
+	if object.IsInteger(l) && object.IsInteger(r) {
+		return vm.executeBinaryIntegerOperation(op, l, r)
 	}
 	if leftType == object.STRING_OBJ && rightType == object.STRING_OBJ {
 		return vm.executeBinaryStringOperation(op, l.(*object.String), r.(*object.String))
@@ -292,26 +334,23 @@ func (vm *VirtualMachine) executeBinaryOperation(op code.OperandCode) error {
 	return fmt.Errorf("unsupported types for binary operation: %s %s", leftType, rightType)
 }
 
-func (vm *VirtualMachine) executeBinaryIntegerOperation(op code.OperandCode, left *object.Integer, right *object.Integer) error {
-	lv := left.Value
-	rv := right.Value
-	var ret int64
-	switch op {
-	case code.Add:
-		ret = lv + rv
-	case code.Sub:
-		ret = lv - rv
-	case code.Mul:
-		ret = lv * rv
-	case code.Div:
-		if rv == 0 {
-			return fmt.Errorf("integer divide by zero")
-		}
-		ret = lv / rv
-	default:
+var arithmeticOperators = map[code.OperandCode]string{
+	code.Add: "+",
+	code.Sub: "-",
+	code.Mul: "*",
+	code.Div: "/",
+}
+
+func (vm *VirtualMachine) executeBinaryIntegerOperation(op code.OperandCode, left, right object.Object) error {
+	operator, ok := arithmeticOperators[op]
+	if !ok {
 		return fmt.Errorf("uknown integer operator: %d", op)
 	}
-	return vm.push(&object.Integer{Value: ret})
+	ret, err := object.Arithmetic(vm.Context.Overflow, operator, left, right)
+	if err != nil {
+		return err
+	}
+	return vm.push(ret)
 }
 
 func (vm *VirtualMachine) executeBinaryStringOperation(op code.OperandCode, left *object.String, right *object.String) error {
@@ -331,15 +370,15 @@ func (vm *VirtualMachine) executeComparison(op code.OperandCode) error {
 	r := vm.pop()
 	l := vm.pop()
 
-	if l.Type() == object.INTEGER_OBJ || r.Type() == object.INTEGER_OBJ {
+	if object.IsInteger(l) || object.IsInteger(r) {
 		return vm.executeIntegerComparison(op, l, r)
 	}
 
 	switch op {
 	case code.Equal:
-		return vm.push(nativeBoolToBooleanObject(r == l))
+		return vm.push(nativeBoolToBooleanObject(object.Equal(l, r)))
 	case code.NotEqual:
-		return vm.push(nativeBoolToBooleanObject(r != l))
+		return vm.push(nativeBoolToBooleanObject(!object.Equal(l, r)))
 	default:
 		return fmt.Errorf("unknown operator: %d (%s %s)", op, l.Type(), r.Type())
 	}
@@ -349,23 +388,20 @@ func (vm *VirtualMachine) executeIntegerComparison(
 	op code.OperandCode,
 	left, right object.Object,
 ) error {
-	l, ok := left.(*object.Integer)
-	if !ok {
+	if !object.IsInteger(left) || !object.IsInteger(right) {
 		return vm.push(nativeBoolToBooleanObject(op != code.Equal))
 	}
-	r, ok := right.(*object.Integer)
-	if !ok {
-		return vm.push(nativeBoolToBooleanObject(op != code.Equal))
+	cmp, err := object.CompareIntegers(left, right)
+	if err != nil {
+		return err
 	}
-	leftValue := l.Value
-	rightValue := r.Value
 	switch op {
 	case code.Equal:
-		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+		return vm.push(nativeBoolToBooleanObject(cmp == 0))
 	case code.NotEqual:
-		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+		return vm.push(nativeBoolToBooleanObject(cmp != 0))
 	case code.GreaterThan:
-		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+		return vm.push(nativeBoolToBooleanObject(cmp > 0))
 	default:
 		return fmt.Errorf("unknown operator: %d", op)
 	}
@@ -374,11 +410,14 @@ func (vm *VirtualMachine) executeIntegerComparison(
 func (vm *VirtualMachine) executeMinusOperator() error {
 	op := vm.pop()
 
-	if op.Type() != object.INTEGER_OBJ {
+	if !object.IsInteger(op) {
 		return fmt.Errorf("unsupported type for negation: %s", op.Type())
 	}
-	value := op.(*object.Integer).Value
-	return vm.push(&object.Integer{Value: -value})
+	ret, err := object.Negate(vm.Context.Overflow, op)
+	if err != nil {
+		return err
+	}
+	return vm.push(ret)
 }
 
 func (vm *VirtualMachine) executeBangOperator() error {
@@ -400,6 +439,8 @@ func (vm *VirtualMachine) executeIndexExpression(left, index object.Object) erro
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.push(object.StringIndex(left.(*object.String), index.(*object.Integer).Value))
 	case left.Type() == object.HASH_OBJ:
 		return vm.executeHashIndex(left, index)
 	default:
@@ -419,18 +460,18 @@ func (vm *VirtualMachine) executeArrayIndex(array, index object.Object) error {
 
 func (vm *VirtualMachine) executeHashIndex(hash, index object.Object) error {
 	hashObj := hash.(*object.Hash)
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 
 	if !ok {
 		return fmt.Errorf("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObj.Pairs[key.HashKey()]
+	value, ok := hashObj.Get(key)
 
 	if !ok {
 		return vm.push(Null)
 	}
-	return vm.push(pair.Value)
+	return vm.push(value)
 }
 
 func isTruthy(obj object.Object) bool {
@@ -460,23 +501,21 @@ func (vm *VirtualMachine) buildArray(startIndex, endIndex int) object.Object {
 }
 
 func (vm *VirtualMachine) buildHash(startIndex, endIndex int) (object.Object, error) {
-	hashedPairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
 	for i := startIndex; i < endIndex; i += 2 {
 		key := vm.stack[i]
 		value := vm.stack[i+1]
 
-		pair := object.HashPair{Key: key, Value: value}
-
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 
 		if !ok {
 			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
 		}
-		hashedPairs[hashKey.HashKey()] = pair
+		hash.Set(hashKey, value)
 	}
 
-	return &object.Hash{Pairs: hashedPairs}, nil
+	return hash, nil
 }
 
 func (vm *VirtualMachine) executeCall(numArgs int) error {
@@ -504,7 +543,19 @@ func (vm *VirtualMachine) callClosure(c *object.Closure, numArgs int) error {
 
 func (vm *VirtualMachine) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	args := vm.stack[vm.sp-numArgs : vm.sp]
-	result := builtin.Fn(args...)
+	if vm.callerBase != vm.Context {
+		vm.caller = vm.Context.WithCaller(vm.callFromBuiltin)
+		vm.callerBase = vm.Context
+	}
+	result := builtin.Fn(vm.caller, args...)
+	if err := vm.callErr; err != nil {
+		vm.callErr = nil
+		return err
+	}
+	// An error from a builtin stops the program, as it does on the evaluator.
+	if err, ok := result.(*object.Error); ok {
+		return errors.New(err.Message)
+	}
 	vm.sp = vm.sp - numArgs - 1
 	if result != nil {
 		vm.push(result)
@@ -514,6 +565,19 @@ func (vm *VirtualMachine) callBuiltin(builtin *object.Builtin, numArgs int) erro
 	return nil
 }
 
+// callFromBuiltin calls fn for a builtin. An error stops the program once the
+// builtin returns, whatever the builtin does with the error object it gets.
+func (vm *VirtualMachine) callFromBuiltin(fn object.Object, args []object.Object) object.Object {
+	result, err := vm.Call(fn, args...)
+	if err != nil {
+		if vm.callErr == nil {
+			vm.callErr = err
+		}
+		return &object.Error{Message: err.Error()}
+	}
+	return result
+}
+
 func (vm *VirtualMachine) pushClosure(constIndex, numfree int) error {
 	constant := vm.constants[constIndex]
 	f, ok := constant.(*object.CompiledFunction)
@@ -524,6 +588,7 @@ func (vm *VirtualMachine) pushClosure(constIndex, numfree int) error {
 	for i := 0; i < numfree; i++ {
 		free[i] = vm.stack[vm.sp-numfree+i]
 	}
+	vm.sp = vm.sp - numfree
 	closure := &object.Closure{Function: f, FreeVariables: free}
 	return vm.push(closure)
 }
@@ -541,12 +606,13 @@ func (vm *VirtualMachine) popFrame() *Frame {
 	return vm.frames[vm.frameIndex]
 }
 func (vm *VirtualMachine) dump() {
-	fmt.Printf("[instructions]\n%s", vm.currentFrame().Instructions())
-	fmt.Println("[global scope]")
+	out := vm.Context.Out
+	fmt.Fprintf(out, "[instructions]\n%s", vm.currentFrame().Instructions())
+	fmt.Fprintln(out, "[global scope]")
 	for i, v := range vm.globals {
 		if v != nil {
-			fmt.Printf("%04d %v\n", i, v.Inspect())
+			fmt.Fprintf(out, "%04d %v\n", i, v.Inspect())
 		}
 	}
-	fmt.Println("[out]")
+	fmt.Fprintln(out, "[out]")
 }
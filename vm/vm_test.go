@@ -1,12 +1,15 @@
 package vm
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/masa-suzu/monkey/ast"
 	"github.com/masa-suzu/monkey/compiler"
 	"github.com/masa-suzu/monkey/lexer"
 	"github.com/masa-suzu/monkey/object"
 	"github.com/masa-suzu/monkey/parser"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -30,6 +33,7 @@ func TestIntegerArithmetic(t *testing.T) {
 func TestIntegerArithmeticError(t *testing.T) {
 	tests := []testCase{
 		{"1 / 0", fmt.Errorf("integer divide by zero")},
+		{"123456789012345678901234567890 / (5 - 5)", fmt.Errorf("integer divide by zero")},
 	}
 	testRunWithError(t, tests)
 }
@@ -50,6 +54,17 @@ func TestBooleanExpressions(t *testing.T) {
 		{"!!true", true},
 		{"!1", false},
 		{"!(if(false){5;})", true},
+		{`"monkey" == "mon" + "key"`, true},
+		{`"monkey" != "monkey"`, false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] != [1, 2, 3]", true},
+		{`{"a": [1], 2: true} == {2: true, "a": [1]}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{"if(false){1} == if(false){2}", true},
+		{"fn(){1} == fn(){1}", false},
+		{"let f = fn(){1}; f == f", true},
+		{`[1] == "[1]"`, false},
 	}
 	testRun(t, tests)
 }
@@ -83,7 +98,22 @@ func TestArrayLiterals(t *testing.T) {
 
 func TestHashLiterals(t *testing.T) {
 	tests := []testCase{
-		{"{}", map[object.HashKey]int64{}},
+		{"{}", []object.HashPair{}},
+		{
+			`{"b": 1, "a": 1 + 1, 3: "c"}`,
+			[]object.HashPair{
+				{Key: &object.String{Value: "b"}, Value: &object.Integer{Value: 1}},
+				{Key: &object.String{Value: "a"}, Value: &object.Integer{Value: 2}},
+				{Key: &object.Integer{Value: 3}, Value: &object.String{Value: "c"}},
+			},
+		},
+		{
+			`{"a": 1, "b": 2, "a": 3}`,
+			[]object.HashPair{
+				{Key: &object.String{Value: "a"}, Value: &object.Integer{Value: 3}},
+				{Key: &object.String{Value: "b"}, Value: &object.Integer{Value: 2}},
+			},
+		},
 	}
 	testRun(t, tests)
 }
@@ -98,10 +128,22 @@ func TestIndexExpression(t *testing.T) {
 		{"{1:1,2:2}[2]", 2},
 		{"{1:1}[0]", Null},
 		{"{}[0]", Null},
+		{"{[1,2]:3}[[1,2]]", 3},
+		{"{[1,[2]]:3}[[1,[2]]]", 3},
+		{"{[1,2]:3}[[2,1]]", Null},
 	}
 	testRun(t, tests)
 }
 
+func TestUnusableHashKeys(t *testing.T) {
+	tests := []testCase{
+		{"{fn(){1}: 1}", fmt.Errorf("unusable as hash key: CLOSURE")},
+		{"{[fn(){1}]: 1}", fmt.Errorf("unusable as hash key: ARRAY")},
+		{"{1: 1}[[fn(){1}]]", fmt.Errorf("unusable as hash key: ARRAY")},
+	}
+	testRunWithError(t, tests)
+}
+
 func TestGlobalLetStatements(t *testing.T) {
 	tests := []testCase{
 		{"let one = 1;one", 1},
@@ -308,6 +350,14 @@ func TestClosures(t *testing.T) {
 	testRun(t, tests)
 }
 
+func TestClosuresAsArguments(t *testing.T) {
+	tests := []testCase{
+		{`let apply = fn(f, x) { f(x) }; let g = fn(n) { apply(fn(x) { x + n }, 1) }; g(2)`, 3},
+		{`let g = fn(a, b) { [fn() { a }, fn() { b }] }; let fs = g(1, 2); fs[0]() + fs[1]()`, 3},
+	}
+	testRun(t, tests)
+}
+
 func TestRecursiveFunctions(t *testing.T) {
 	tests := []testCase{
 		{
@@ -370,16 +420,302 @@ func TestBuiltinFunctions(t *testing.T) {
 		{
 			`puts("monkey")`, Null,
 		},
-		{
-			`help()`, Null,
-		},
-		{
-			`exit()`, Null,
-		},
 	}
 	testRun(t, tests)
 }
 
+func TestBuiltinFunctionsWithContext(t *testing.T) {
+	tests := []struct {
+		in      string
+		stdin   string
+		want    interface{}
+		wantOut string
+	}{
+		{`puts("monkey")`, "", Null, "monkey\n"},
+		{`puts(1, [2, 3])`, "", Null, "1\n[2, 3]\n"},
+		{`gets()`, "monkey\nbusiness\n", "monkey", ""},
+		{`gets(); gets()`, "monkey\nbusiness", "business", ""},
+		{`gets()`, "", Null, ""},
+	}
+
+	for _, tt := range tests {
+		p := parse(tt.in)
+		c := compiler.New()
+		err := c.Compile(p)
+		if err != nil {
+			t.Fatalf("compiler got error: %s", err)
+		}
+
+		out := &bytes.Buffer{}
+		vm := New(c.ByteCode())
+		vm.Context = newTestContext(tt.stdin, out)
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm.Run got error: %s", err)
+		}
+
+		testExpectedObject(t, tt.in, tt.want, vm.LastPoppedStackElement())
+		if out.String() != tt.wantOut {
+			t.Errorf("%s wrote wrong output. want=%q, got=%q", tt.in, tt.wantOut, out.String())
+		}
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []testCase{
+		{`json_parse("[1, 2]")`, []int{1, 2}},
+		{`json_parse("{\"a\": {\"b\": [1]}}")["a"]["b"][0]`, 1},
+		{`json_parse("\"monkey\"")`, "monkey"},
+		{`json_parse("null")`, Null},
+		{`json_parse("1.5")`, &object.Error{Message: "json_parse: number 1.5 is not an integer"}},
+		{`json_stringify({"b": [1, "two"], "a": json_parse("null")})`, `{"b":[1,"two"],"a":null}`},
+		{`json_stringify([fn() {}])`, &object.Error{Message: "json_stringify: CLOSURE can not be encoded"}},
+	}
+	testRun(t, tests)
+}
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []testCase{
+		{`push([1, 2], 3)`, []int{1, 2, 3}},
+		{`pop([1, 2, 3])`, 3},
+		{`pop([])`, Null},
+		{`concat([1], [2, 3])`, []int{1, 2, 3}},
+		{`slice([1, 2, 3, 4], -3, -1)`, []int{2, 3}},
+		{`reverse([1, 2, 3])`, []int{3, 2, 1}},
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`first(sort([pow(2, 70), 1]))`, 1},
+		{`range(2, 5)`, []int{2, 3, 4}},
+		{`range(9223372036854775806, 9223372036854775807, 2)`, []int{9223372036854775806}},
+		{`range(100000000)`, &object.Error{Message: "`range` would return more than 16777216 elements"}},
+		{`flatten([[1], 2, [3]])`, []int{1, 2, 3}},
+		{`unique([1, 1, 2])`, []int{1, 2}},
+		{`push(1, 2)`, &object.Error{Message: "argument to `push` must be ARRAY, got INTEGER"}},
+	}
+	testRun(t, tests)
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []testCase{
+		{`len("héllo")`, 5},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[5]`, Null},
+		{`join(split("a,b", ","), "+")`, "a+b"},
+		{`upper(trim(" monkey "))`, "MONKEY"},
+		{`index_of("añbñ", "b")`, 2},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("abc", 1, 9223372036854775807)`, "bc"},
+		{`repeat("ab", 9223372036854775807)`, &object.Error{Message: "result of `repeat` would be longer than 1073741824 bytes"}},
+		{`ord(chr(241))`, 241},
+		{`format("%s=%03d", "x", 7)`, "x=007"},
+		{`map(chars("ab"), upper)`, []string{"A", "B"}},
+		{`format("%d", "a")`, &object.Error{Message: "format: %d wants INTEGER, got STRING"}},
+	}
+	testRun(t, tests)
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []testCase{
+		{`keys({"b": 1, "a": 2})`, []string{"b", "a"}},
+		{`values({"b": 1, "a": 2})`, []int{1, 2}},
+		{`has({"a": 1}, "a")`, true},
+		{`get({"a": 1}, "b", 7)`, 7},
+		{`len(keys(delete({"a": 1, "b": 2}, "a")))`, 1},
+		{`merge({"a": 1}, {"a": 2})["a"]`, 2},
+		{`from_entries([["a", 1], ["b", 2]])`, []object.HashPair{
+			{Key: &object.String{Value: "a"}, Value: &object.Integer{Value: 1}},
+			{Key: &object.String{Value: "b"}, Value: &object.Integer{Value: 2}},
+		}},
+		{`keys(1)`, &object.Error{Message: "argument to `keys` must be HASH, got INTEGER"}},
+	}
+	testRun(t, tests)
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []testCase{
+		{`abs(-5)`, 5},
+		{`max(3, 1, 2)`, 3},
+		{`min([4, 9, 2])`, 2},
+		{`pow(2, 10)`, 1024},
+		{`sqrt(17)`, 4},
+		{`floor(-7, 2)`, -4},
+		{`ceil(-7, 2)`, -3},
+		{`modulo(-7, 3)`, 2},
+		{`clamp(5, 0, 3)`, 3},
+		{`gcd(12, 18)`, 6},
+		{`pow(2, -1)`, &object.Error{Message: "exponent of `pow` must not be negative, got -1"}},
+		{`pow(2, 100000000000)`, &object.Error{Message: "`pow` would return more than 16777216 bits"}},
+		{`pow(-1, 100000000001)`, -1},
+	}
+	testRun(t, tests)
+}
+
+func TestBigIntegers(t *testing.T) {
+	bigInt := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 10)
+		return n
+	}
+	tests := []testCase{
+		{`123456789012345678901234567890`, bigInt("123456789012345678901234567890")},
+		{`9223372036854775807 + 1`, bigInt("9223372036854775808")},
+		{`9223372036854775808 - 1`, 9223372036854775807},
+		{`-9223372036854775808`, -9223372036854775807 - 1},
+		{`let x = 9223372036854775807; x * x`, bigInt("85070591730234615847396907784232501249")},
+		{`9223372036854775808 > 1`, true},
+		{`9223372036854775808 == 9223372036854775807 + 1`, true},
+		{`{9223372036854775808: 1}[9223372036854775807 + 1]`, 1},
+		{`let f = fn(n) { if (n < 2) { return 1; } n * f(n - 1) }; f(25)`, bigInt("15511210043330985984000000")},
+	}
+	testRun(t, tests)
+}
+
+func TestRunDeserializedByteCode(t *testing.T) {
+	c := compiler.New()
+	err := c.Compile(parse(`let f = fn(x) { x * 9223372036854775807 }; [f(2), "ok"]`))
+	if err != nil {
+		t.Fatalf("compiler got error: %s", err)
+	}
+	data, err := c.ByteCode().MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary got error: %s", err)
+	}
+	byteCode := &compiler.ByteCode{}
+	if err := byteCode.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary got error: %s", err)
+	}
+
+	vm := New(byteCode)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm.Run got error: %s", err)
+	}
+	got := vm.LastPoppedStackElement().Inspect()
+	if got != "[18446744073709551614, ok]" {
+		t.Errorf("wrong result. want=%q, got=%q", "[18446744073709551614, ok]", got)
+	}
+}
+
+func TestTypeBuiltins(t *testing.T) {
+	tests := []testCase{
+		{`type(fn(x) { x })`, "FUNCTION"},
+		{`let y = 1; type(fn(x) { x + y })`, "FUNCTION"},
+		{`type(9223372036854775808)`, "INTEGER"},
+		{`type(len)`, "BUILTIN"},
+		{`is_fn(fn() {})`, true},
+		{`is_int("1")`, false},
+		{`str(42)`, "42"},
+		{`int("42") + 1`, 43},
+		{`arity(fn(a, b) { a })`, 2},
+		{`let y = 1; arity(fn(a) { a + y })`, 1},
+		{`arity(map)`, 2},
+		{`int("4x")`, &object.Error{Message: `could not convert "4x" to INTEGER`}},
+	}
+	testRun(t, tests)
+}
+
+func TestBuiltinCallbacks(t *testing.T) {
+	tests := []testCase{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`let n = 10; map([1, 2], fn(x) { x + n })`, []int{11, 12}},
+		{`let f = fn(n) { map([1, 2], fn(x) { x + n }) }; f(5)`, []int{6, 7}},
+		{`map([[1], [2, 3]], len)`, []int{1, 2}},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, []int{3, 4}},
+		{`reduce([1, 2, 3], 0, fn(acc, x) { acc + x })`, 6},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, 2},
+		{`any([1, 2], fn(x) { x > 1 })`, true},
+		{`all([1, 2], fn(x) { x > 1 })`, false},
+		{`sort([1, 3, 2], fn(a, b) { a > b })`, []int{3, 2, 1}},
+		{`map([[3, 1], [2]], fn(a) { first(sort(a, fn(x, y) { x < y })) })`, []int{1, 2}},
+		{`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; map(range(7), fib)`,
+			[]int{0, 1, 1, 2, 3, 5, 8}},
+		{`let r = map([1], fn(x) { return x; 99 }); [r[0], 1 + 1]`, []int{1, 2}},
+		{`1 + reduce(map([1, 2], fn(x) { x * x }), 0, fn(a, b) { a + b }) * 2`, 11},
+		{`sort([1, 2], fn(a, b) { 1 })`, &object.Error{Message: "comparator of `sort` must return BOOLEAN, got INTEGER"}},
+	}
+	testRun(t, tests)
+}
+
+func TestBuiltinCallbackErrors(t *testing.T) {
+	tests := []testCase{
+		{`map([1, 0], fn(x) { 1 / x })`, fmt.Errorf("integer divide by zero")},
+		{`map([1], fn(x, y) { x })`, fmt.Errorf("wrong number of arguments: want=2, got=1")},
+		{`map([1], fn(x) { map([x], fn(y) { y / 0 }) })`, fmt.Errorf("integer divide by zero")},
+	}
+	testRunWithError(t, tests)
+}
+
+func TestBuiltinErrorsStopTheProgram(t *testing.T) {
+	tests := []testCase{
+		{`len(1); 5`, fmt.Errorf("argument to `len` not supported, got INTEGER")},
+		{`let x = len(1); x`, fmt.Errorf("argument to `len` not supported, got INTEGER")},
+		{`map([1, 2], len)`, fmt.Errorf("argument to `len` not supported, got INTEGER")},
+		{`let f = fn() { first(1) }; [f(), 2]`, fmt.Errorf("argument to first must be ARRAY, got INTEGER")},
+	}
+	testRunWithError(t, tests)
+}
+
+func TestCall(t *testing.T) {
+	p := parse(`let add = fn(a, b) { a + b }; let twice = fn(f, x) { f(f(x)) };`)
+	c := compiler.New()
+	if err := c.Compile(p); err != nil {
+		t.Fatalf("compiler got error: %s", err)
+	}
+	vm := New(c.ByteCode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm.Run got error: %s", err)
+	}
+
+	add, twice := vm.globals[0], vm.globals[1]
+	got, err := vm.Call(add, &object.Integer{Value: 1}, &object.Integer{Value: 2})
+	if err != nil {
+		t.Fatalf("vm.Call got error: %s", err)
+	}
+	testExpectedObject(t, "add(1, 2)", 3, got)
+
+	got, err = vm.Call(twice, object.GetBuiltinName("rest"), &object.Array{Elements: []object.Object{
+		&object.Integer{Value: 1}, &object.Integer{Value: 2}, &object.Integer{Value: 3},
+	}})
+	if err != nil {
+		t.Fatalf("vm.Call got error: %s", err)
+	}
+	testExpectedObject(t, "twice(rest, [1, 2, 3])", []int{3}, got)
+
+	if _, err := vm.Call(add, &object.Integer{Value: 1}); err == nil {
+		t.Errorf("vm.Call with wrong number of arguments got no error")
+	}
+}
+
+func TestClosuresInExpressions(t *testing.T) {
+	tests := []testCase{
+		// The free variables a closure captures must be taken off the stack,
+		// or the operators around it would use them as operands.
+		{`fn(a) { 1 + fn() { a }() }(10)`, 11},
+		{`fn(a, b) { [fn() { a + b }(), a] }(1, 2)`, []int{3, 1}},
+		{`fn(a) { let g = fn() { a }; g() * 2 }(4)`, 8},
+	}
+	testRun(t, tests)
+}
+
+func TestReplacingContext(t *testing.T) {
+	p := parse(`puts("first")`)
+	c := compiler.New()
+	if err := c.Compile(p); err != nil {
+		t.Fatalf("compiler got error: %s", err)
+	}
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	vm := New(c.ByteCode())
+	vm.Context = newTestContext("", first)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm.Run got error: %s", err)
+	}
+
+	vm.Context = newTestContext("", second)
+	if _, err := vm.Call(object.GetBuiltinName("puts"), &object.String{Value: "second"}); err != nil {
+		t.Fatalf("vm.Call got error: %s", err)
+	}
+	if first.String() != "first\n" || second.String() != "second\n" {
+		t.Errorf("builtins wrote %q to the first context and %q to the second", first.String(), second.String())
+	}
+}
+
 func TestIssue001(t *testing.T) {
 	tests := []testCase{
 		{"return 1;", 1},
@@ -402,8 +738,16 @@ func testRun(t *testing.T, tests []testCase) {
 			}
 
 			vm := New(c.ByteCode())
+			vm.Context = newTestContext("", &bytes.Buffer{})
 			err = vm.Run()
 
+			// Errors returned by builtins stop the program.
+			if want, ok := tt.want.(*object.Error); ok {
+				if err == nil || err.Error() != want.Message {
+					t.Fatalf("vm.Run got error %v, want %s", err, want.Message)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatalf("vm.Run got error: %s", err)
 			}
@@ -415,6 +759,10 @@ func testRun(t *testing.T, tests []testCase) {
 	}
 }
 
+func newTestContext(in string, out *bytes.Buffer) *object.Context {
+	return object.NewContext(strings.NewReader(in), out)
+}
+
 func testRunWithError(t *testing.T, tests []testCase) {
 	t.Helper()
 
@@ -472,22 +820,47 @@ func testExpectedObject(
 				t.Errorf("testIntegerObject failed: %s", err)
 			}
 		}
-	case map[object.HashKey]int64:
+	case *big.Int:
+		integer, ok := got.(*object.BigInt)
+		if !ok {
+			t.Errorf("%s failed: object is not BigInt. got=%T (%+v)", name, got, got)
+			return
+		}
+		if integer.Value.Cmp(want) != 0 {
+			t.Errorf("%s failed: wrong value. want=%s, got=%s", name, want, integer.Value)
+		}
+	case []string:
+		array, ok := got.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array:%T (%+v)", got, got)
+			return
+		}
+		if len(array.Elements) != len(want) {
+			t.Errorf("wrong num of elements. want=%d, got=%d", len(want), len(array.Elements))
+			return
+		}
+		for i, value := range want {
+			err := testStringObject(value, array.Elements[i])
+			if err != nil {
+				t.Errorf("testStringObject failed: %s", err)
+			}
+		}
+	case []object.HashPair:
 		hash, ok := got.(*object.Hash)
 		if !ok {
 			t.Errorf("object is not Hash. got=%T (%+v)", got, got)
+			return
 		}
-		if len(hash.Pairs) != len(want) {
-			t.Errorf("wrong num of pairs. want=%d, got=%d", len(want), len(hash.Pairs))
+		if hash.Len() != len(want) {
+			t.Errorf("wrong num of pairs. want=%d, got=%d", len(want), hash.Len())
+			return
 		}
-		for key, value := range want {
-			pair, ok := hash.Pairs[key]
-			if !ok {
-				t.Errorf("no pair for given key `%v` in pairs", key.Value)
+		for i, pair := range hash.Pairs() {
+			if pair.Key.Inspect() != want[i].Key.Inspect() {
+				t.Errorf("pair %d has wrong key. want=%s, got=%s", i, want[i].Key.Inspect(), pair.Key.Inspect())
 			}
-			err := testIntegerObject(value, pair.Value)
-			if err != nil {
-				t.Errorf("testIntegerObject failed: %s", err)
+			if pair.Value.Inspect() != want[i].Value.Inspect() {
+				t.Errorf("pair %d has wrong value. want=%s, got=%s", i, want[i].Value.Inspect(), pair.Value.Inspect())
 			}
 		}
 